@@ -0,0 +1,78 @@
+// Command ideas-migrate copies ideas stored in a single object or file to the
+// objects layout, with an object or file per idea.
+//
+//	ideas-migrate -from-backend filesystem -from-path ideas.json -to-backend filesystem -to-path ideas
+//	ideas-migrate -from-bucket my-bucket -from-object ideas.json -to-bucket my-bucket -to-prefix ideas/
+//
+// The source is left untouched, so the server can be switched to the objects
+// layout once the copy is done. Running it again overwrites the copied ideas.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/ideas"
+)
+
+func main() {
+	var from, to config.Ideas
+
+	flag.StringVar(&from.Backend, "from-backend", config.IdeasBackendGCS, `backend of the source, "gcs" or "filesystem"`)
+	flag.StringVar(&from.Bucket, "from-bucket", "", "GCS bucket of the source")
+	flag.StringVar(&from.Object, "from-object", "", "GCS object with every idea")
+	flag.StringVar(&from.Path, "from-path", "", "JSON file with every idea")
+
+	flag.StringVar(&to.Backend, "to-backend", config.IdeasBackendGCS, `backend of the destination, "gcs" or "filesystem"`)
+	flag.StringVar(&to.Bucket, "to-bucket", "", "GCS bucket of the destination")
+	flag.StringVar(&to.Prefix, "to-prefix", "ideas/", "prefix of the GCS objects of the destination")
+	flag.StringVar(&to.Path, "to-path", "", "directory of the destination")
+	flag.Parse()
+
+	if err := validate(from, to); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid flags: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	to.Layout = config.IdeasLayoutObjects
+	migrated, err := ideas.MigrateToObjects(context.Background(), from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to migrate ideas: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("migrated %d ideas\n", migrated)
+}
+
+func validate(from, to config.Ideas) error {
+	switch from.Backend {
+	case config.IdeasBackendGCS:
+		if from.Bucket == "" || from.Object == "" {
+			return fmt.Errorf("-from-bucket and -from-object are required with the gcs backend")
+		}
+	case config.IdeasBackendFilesystem:
+		if from.Path == "" {
+			return fmt.Errorf("-from-path is required with the filesystem backend")
+		}
+	default:
+		return fmt.Errorf("unknown -from-backend %q", from.Backend)
+	}
+
+	switch to.Backend {
+	case config.IdeasBackendGCS:
+		if to.Bucket == "" {
+			return fmt.Errorf("-to-bucket is required with the gcs backend")
+		}
+	case config.IdeasBackendFilesystem:
+		if to.Path == "" {
+			return fmt.Errorf("-to-path is required with the filesystem backend")
+		}
+	default:
+		return fmt.Errorf("unknown -to-backend %q", to.Backend)
+	}
+	return nil
+}
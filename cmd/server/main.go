@@ -1,10 +1,34 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/logging"
 	"github.com/jaehnri/website-backend/internal/server"
 )
 
 func main() {
-	s := server.NewServer(":8080")
+	cfg, options, err := config.Load(os.Args[1:])
+
+	// Broken configs are printed too, as that's when it's most useful.
+	if options != nil && options.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to print configuration: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if options.PrintConfig {
+		return
+	}
+
+	logging.Setup(cfg.Logging, os.Stderr)
+
+	s := server.NewServer(cfg)
 	s.Run()
 }
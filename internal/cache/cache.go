@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/metrics"
+)
+
+// TTLCache is a thread-safe in-memory cache whose entries expire after a fixed
+// TTL. It holds up to maxEntries entries: expired ones are swept every TTL,
+// and the ones closest to expiring are evicted when it's full.
+type TTLCache[V any] struct {
+	// name identifies the cache in metrics.
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	// lock protects entries and nextSweep.
+	lock    sync.Mutex
+	entries map[string]entry[V]
+
+	// nextSweep is when expired entries are removed next.
+	nextSweep time.Time
+}
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func NewTTLCache[V any](name string, ttl time.Duration, maxEntries int) *TTLCache[V] {
+	return &TTLCache[V]{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]entry[V]),
+		nextSweep:  time.Now().Add(ttl),
+	}
+}
+
+// Get returns the cached value for key, if it exists and hasn't expired yet.
+func (c *TTLCache[V]) Get(key string) (V, bool) {
+	value, hit := c.get(key)
+	metrics.ObserveCacheLookup(c.name, hit)
+	return value, hit
+}
+
+func (c *TTLCache[V]) get(key string) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, exists := c.entries[key]
+	if !exists {
+		var zero V
+		return zero, false
+	}
+
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Set caches value under key for the cache TTL.
+func (c *TTLCache[V]) Set(key string, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	if _, exists := c.entries[key]; !exists {
+		c.makeRoom(now)
+	}
+
+	c.entries[key] = entry[V]{
+		value:     value,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// makeRoom sweeps expired entries if it's time to, or if the cache is full,
+// then evicts the entry closest to expiring if it's still full.
+func (c *TTLCache[V]) makeRoom(now time.Time) {
+	if now.Before(c.nextSweep) && len(c.entries) < c.maxEntries {
+		return
+	}
+
+	var oldestKey string
+	var oldest time.Time
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest.IsZero() || e.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, e.expiresAt
+		}
+	}
+	c.nextSweep = now.Add(c.ttl)
+
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// Delete evicts key from the cache.
+func (c *TTLCache[V]) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Clear evicts every entry from the cache.
+func (c *TTLCache[V]) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	clear(c.entries)
+}
@@ -0,0 +1,477 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the whole server configuration. Each field can be set, from
+// lowest to highest precedence, by its default value, the config file, its
+// environment variable and its command-line flag.
+//
+// Field tags:
+//   - yaml: key in the config file. Flags are named after the full key path,
+//     e.g. "spotify.client_id" becomes --spotify.client-id.
+//   - env: environment variable. For secrets, <env>_FILE can point to a file
+//     holding the value instead.
+//   - default: value used when nothing else sets the field.
+type Config struct {
+	HTTPAddress    string        `yaml:"http_address" env:"HTTP_ADDRESS_ENV" default:":8080"`
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT_ENV" default:"10s"`
+
+	// ShutdownDelay is how long the server keeps serving, with readiness failing,
+	// after a termination signal. It gives load balancers time to stop sending
+	// new requests.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY_ENV" default:"3s"`
+
+	// ShutdownTimeout is how long in-flight requests have to finish afterwards.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT_ENV" default:"5s"`
+
+	// APIToken is required by authenticated routes, e.g. POST /ideas. If empty,
+	// authenticated routes reject every request.
+	APIToken Secret `yaml:"api_token" env:"API_TOKEN_ENV"`
+
+	Logging      Logging      `yaml:"logging"`
+	Health       Health       `yaml:"health"`
+	Tracing      Tracing      `yaml:"tracing"`
+	CORS         CORS         `yaml:"cors"`
+	RateLimit    RateLimit    `yaml:"rate_limit"`
+	NowPlaying   NowPlaying   `yaml:"now_playing"`
+	Spotify      Spotify      `yaml:"spotify"`
+	LastFM       LastFM       `yaml:"lastfm"`
+	ListenBrainz ListenBrainz `yaml:"listenbrainz"`
+	Ideas        Ideas        `yaml:"ideas"`
+	Listening    Listening    `yaml:"listening"`
+}
+
+type Logging struct {
+	// Format is "json", for production, or "text", for local development.
+	Format string `yaml:"format" env:"LOG_FORMAT_ENV" default:"json"`
+
+	// Level is "debug", "info", "warn" or "error".
+	Level string `yaml:"level" env:"LOG_LEVEL_ENV" default:"info"`
+}
+
+type Health struct {
+	// CacheTTL is how long readiness check results are reused.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"HEALTH_CACHE_TTL_ENV" default:"10s"`
+
+	// CheckTimeout bounds each readiness check.
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT_ENV" default:"3s"`
+}
+
+type Tracing struct {
+	// Exporter is "none", "stdout" or "otlp".
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER_ENV" default:"none"`
+
+	// OTLPEndpoint is the URL traces are sent to by the OTLP exporter, e.g.
+	// http://localhost:4318/v1/traces. If empty, the standard OTEL_EXPORTER_OTLP_*
+	// environment variables are used.
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT_ENV"`
+
+	ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME_ENV" default:"website-backend"`
+}
+
+type CORS struct {
+	// AllowedOrigins are either exact, e.g. "https://joaohenri.io", or wildcard
+	// subdomains, e.g. "https://*.joaohenri.io". "*" allows any origin.
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS_ENV" default:"*"`
+
+	// AllowCredentials can't be used together with "*".
+	AllowCredentials bool `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS_ENV"`
+}
+
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED_ENV" default:"true"`
+
+	// Default limits each client on each route, formatted as <requests>/<period>.
+	Default string `yaml:"default" env:"RATE_LIMIT_DEFAULT_ENV" default:"60/1m"`
+
+	// Routes override the default by route pattern, formatted as
+	// <pattern>=<requests>/<period>, e.g. "POST /ideas=10/1h".
+	Routes []string `yaml:"routes" env:"RATE_LIMIT_ROUTES_ENV" default:"POST /ideas=10/1h"`
+
+	// TrustedProxies are IPs or CIDRs allowed to set X-Forwarded-For.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"RATE_LIMIT_TRUSTED_PROXIES_ENV"`
+
+	// MaxKeys bounds how many clients are tracked at once.
+	MaxKeys int `yaml:"max_keys" env:"RATE_LIMIT_MAX_KEYS_ENV" default:"10000"`
+}
+
+type NowPlaying struct {
+	// Enabled serves GET /now-playing.
+	Enabled bool `yaml:"enabled" env:"NOW_PLAYING_ENABLED_ENV" default:"true"`
+
+	// Providers are tried in order until one succeeds.
+	Providers []string `yaml:"providers" env:"NOW_PLAYING_PROVIDERS_ENV" default:"spotify"`
+}
+
+type Spotify struct {
+	// Enabled serves GET /recently-played and /playlists, and allows other
+	// modules to use Spotify.
+	Enabled bool `yaml:"enabled" env:"SPOTIFY_ENABLED_ENV" default:"true"`
+
+	ClientID     string `yaml:"client_id" env:"CLIENT_ID_ENV"`
+	ClientSecret Secret `yaml:"client_secret" env:"CLIENT_SECRET_ENV"`
+	RefreshToken Secret `yaml:"refresh_token" env:"REFRESH_TOKEN_ENV"`
+
+	// PlaylistsCacheControl is the Cache-Control header of /playlists. Playlists
+	// change rarely, so browsers and CDNs can keep them for a while.
+	PlaylistsCacheControl string `yaml:"playlists_cache_control" env:"PLAYLISTS_CACHE_CONTROL_ENV" default:"public, max-age=3600"`
+}
+
+type LastFM struct {
+	APIKey  Secret `yaml:"api_key" env:"LASTFM_API_KEY_ENV"`
+	User    string `yaml:"user" env:"LASTFM_USER_ENV"`
+	BaseURL string `yaml:"base_url" env:"LASTFM_BASE_URL_ENV" default:"https://ws.audioscrobbler.com/2.0/"`
+}
+
+type ListenBrainz struct {
+	User string `yaml:"user" env:"LISTENBRAINZ_USER_ENV"`
+
+	// Token is optional, but authenticated requests get higher rate limits.
+	Token   Secret `yaml:"token" env:"LISTENBRAINZ_TOKEN_ENV"`
+	BaseURL string `yaml:"base_url" env:"LISTENBRAINZ_BASE_URL_ENV" default:"https://api.listenbrainz.org"`
+}
+
+type Ideas struct {
+	// Enabled serves /ideas.
+	Enabled bool `yaml:"enabled" env:"IDEAS_ENABLED_ENV" default:"true"`
+
+	// Backend is "gcs", "filesystem" or "sqlite".
+	Backend string `yaml:"backend" env:"IDEAS_BACKEND_ENV" default:"gcs"`
+
+	// Layout is either "single", a single object or file with every idea, or
+	// "objects", an object or file per idea.
+	Layout string `yaml:"layout" env:"IDEAS_LAYOUT_ENV" default:"single"`
+
+	Bucket string `yaml:"bucket" env:"GCS_BUCKET_ENV"`
+
+	// Object holds every idea with the single layout.
+	Object string `yaml:"object" env:"GCS_OBJECT_ENV"`
+
+	// Prefix is prepended to the names of objects with the objects layout.
+	Prefix string `yaml:"prefix" env:"GCS_PREFIX_ENV" default:"ideas/"`
+
+	// Path is the JSON file used by the filesystem backend, or the directory
+	// with the objects layout. It's the database file of the sqlite backend.
+	Path string `yaml:"path" env:"IDEAS_PATH_ENV"`
+
+	// RevisionsPath is the directory where the filesystem backend keeps past
+	// versions of Path. Defaults to Path with a ".revisions" suffix.
+	RevisionsPath string `yaml:"revisions_path" env:"IDEAS_REVISIONS_PATH_ENV"`
+
+	// MaxRevisions is how many past versions of Path the filesystem backend
+	// keeps. Older ones are deleted.
+	MaxRevisions int `yaml:"max_revisions" env:"IDEAS_MAX_REVISIONS_ENV" default:"100"`
+
+	// CacheControl is the Cache-Control header of GET /ideas.
+	CacheControl string `yaml:"cache_control" env:"IDEAS_CACHE_CONTROL_ENV" default:"public, max-age=60"`
+
+	// CacheTTL is how long ideas are kept in memory. Posted ideas are added
+	// to the cache, but ideas posted by other replicas take up to CacheTTL to show.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"IDEAS_CACHE_TTL_ENV" default:"1m"`
+
+	// SchedulerInterval is how often scheduled ideas are checked and published.
+	SchedulerInterval time.Duration `yaml:"scheduler_interval" env:"IDEAS_SCHEDULER_INTERVAL_ENV" default:"1m"`
+
+	// TrashRetention is how long deleted ideas are kept in the trash before
+	// they're purged for good.
+	TrashRetention time.Duration `yaml:"trash_retention" env:"IDEAS_TRASH_RETENTION_ENV" default:"720h"`
+
+	// PurgeInterval is how often ideas past TrashRetention are purged.
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"IDEAS_PURGE_INTERVAL_ENV" default:"1h"`
+
+	// CompactionInterval is how often the index of the objects layout is
+	// rewritten, so reads don't fetch ideas one by one.
+	CompactionInterval time.Duration `yaml:"compaction_interval" env:"IDEAS_COMPACTION_INTERVAL_ENV" default:"1h"`
+
+	Feed IdeasFeed `yaml:"feed"`
+}
+
+// IdeasFeed is the metadata of the RSS, Atom and JSON feeds of ideas.
+type IdeasFeed struct {
+	Title       string `yaml:"title" env:"IDEAS_FEED_TITLE_ENV" default:"João Henri's ideas"`
+	Description string `yaml:"description" env:"IDEAS_FEED_DESCRIPTION_ENV" default:"Ideas I had. Or thoughts. Or something."`
+	Author      string `yaml:"author" env:"IDEAS_FEED_AUTHOR_ENV" default:"João Henri"`
+
+	// Link is the page where ideas are shown. Items link to Link#<id>.
+	Link string `yaml:"link" env:"IDEAS_FEED_LINK_ENV" default:"https://joaohenri.io/ideas"`
+
+	// BaseURL is the public URL of this API, used for the feeds to link to
+	// themselves. If empty, it's guessed from the request.
+	BaseURL string `yaml:"base_url" env:"IDEAS_FEED_BASE_URL_ENV"`
+}
+
+type Listening struct {
+	// Enabled takes monthly snapshots of my Spotify top items and serves
+	// /listening. Requires Spotify.
+	Enabled bool `yaml:"enabled" env:"LISTENING_ENABLED_ENV"`
+
+	// Exactly one of Bucket and Dir must be set.
+	Bucket string `yaml:"bucket" env:"LISTENING_BUCKET_ENV"`
+	Dir    string `yaml:"dir" env:"LISTENING_DIR_ENV"`
+
+	// SnapshotInterval is how often the snapshot job checks for a missing snapshot.
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" env:"LISTENING_SNAPSHOT_INTERVAL_ENV" default:"24h"`
+}
+
+const (
+	IdeasBackendGCS        = "gcs"
+	IdeasBackendFilesystem = "filesystem"
+	IdeasBackendSQLite     = "sqlite"
+
+	IdeasLayoutSingle  = "single"
+	IdeasLayoutObjects = "objects"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// NowPlayingProviders lists the supported now playing providers.
+var NowPlayingProviders = []string{"spotify", "lastfm", "listenbrainz"}
+
+// validate reports every problem of the config at once. The sections of
+// modules, e.g. spotify or ideas, are validated by the modules themselves, so
+// a broken section only degrades its module.
+func (c *Config) validate() error {
+	var errs []error
+
+	if c.HTTPAddress == "" {
+		errs = append(errs, missing("http_address", "HTTP_ADDRESS_ENV"))
+	}
+	if c.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("request_timeout must be positive"))
+	}
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("shutdown_delay can't be negative"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	if c.Health.CacheTTL < 0 {
+		errs = append(errs, errors.New("health.cache_ttl can't be negative"))
+	}
+	if c.Health.CheckTimeout <= 0 {
+		errs = append(errs, errors.New("health.check_timeout must be positive"))
+	}
+
+	if c.Logging.Format != LogFormatJSON && c.Logging.Format != LogFormatText {
+		errs = append(errs, fmt.Errorf("logging.format: unknown format %q, must be %q or %q", c.Logging.Format, LogFormatJSON, LogFormatText))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Logging.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("logging.level: unknown level %q, must be debug, info, warn or error", c.Logging.Level))
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if c.Tracing.OTLPEndpoint != "" {
+			errs = append(errs, validateURL("tracing.otlp_endpoint", c.Tracing.OTLPEndpoint)...)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: unknown exporter %q, must be %q, %q or %q", c.Tracing.Exporter, TracingExporterNone, TracingExporterStdout, TracingExporterOTLP))
+	}
+
+	if slices.Contains(c.CORS.AllowedOrigins, "*") && c.CORS.AllowCredentials {
+		errs = append(errs, errors.New("cors.allow_credentials can't be used when cors.allowed_origins has \"*\""))
+	}
+
+	if c.RateLimit.Enabled {
+		errs = append(errs, c.validateRateLimit()...)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateRateLimit() []error {
+	var errs []error
+
+	if err := validateLimit(c.RateLimit.Default); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limit.default: %v", err))
+	}
+	for _, routeLimit := range c.RateLimit.Routes {
+		pattern, limit, found := strings.Cut(routeLimit, "=")
+		if !found || pattern == "" {
+			errs = append(errs, fmt.Errorf("rate_limit.routes: %q must be formatted as <pattern>=<requests>/<period>", routeLimit))
+			continue
+		}
+		if err := validateLimit(limit); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.routes: %v", err))
+		}
+	}
+
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		if cidrErr != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("rate_limit.trusted_proxies: %q must be an IP or a CIDR", proxy))
+		}
+	}
+
+	if c.RateLimit.MaxKeys <= 0 {
+		errs = append(errs, errors.New("rate_limit.max_keys must be positive"))
+	}
+
+	return errs
+}
+
+func validateLimit(limit string) error {
+	requestsStr, periodStr, found := strings.Cut(limit, "/")
+	if !found {
+		return fmt.Errorf("%q must be formatted as <requests>/<period>, e.g. 10/1h", limit)
+	}
+	if requests, err := strconv.Atoi(requestsStr); err != nil || requests <= 0 {
+		return fmt.Errorf("%q must allow a positive number of requests", limit)
+	}
+	if period, err := time.ParseDuration(periodStr); err != nil || period <= 0 {
+		return fmt.Errorf("%q must have a positive period, e.g. 1m", limit)
+	}
+	return nil
+}
+
+// ValidateNowPlaying reports every problem of the now_playing section, and of the providers it uses.
+func (c *Config) ValidateNowPlaying() error {
+	var errs []error
+
+	if len(c.NowPlaying.Providers) == 0 {
+		errs = append(errs, missing("now_playing.providers", "NOW_PLAYING_PROVIDERS_ENV"))
+	}
+	for _, provider := range c.NowPlaying.Providers {
+		if !slices.Contains(NowPlayingProviders, provider) {
+			errs = append(errs, fmt.Errorf("now_playing.providers: unknown provider %q, must be one of %v", provider, NowPlayingProviders))
+		}
+	}
+
+	if slices.Contains(c.NowPlaying.Providers, "lastfm") {
+		if c.LastFM.APIKey == "" {
+			errs = append(errs, missing("lastfm.api_key", "LASTFM_API_KEY_ENV"))
+		}
+		if c.LastFM.User == "" {
+			errs = append(errs, missing("lastfm.user", "LASTFM_USER_ENV"))
+		}
+		errs = append(errs, validateURL("lastfm.base_url", c.LastFM.BaseURL)...)
+	}
+
+	if slices.Contains(c.NowPlaying.Providers, "listenbrainz") {
+		if c.ListenBrainz.User == "" {
+			errs = append(errs, missing("listenbrainz.user", "LISTENBRAINZ_USER_ENV"))
+		}
+		errs = append(errs, validateURL("listenbrainz.base_url", c.ListenBrainz.BaseURL)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateSpotify reports every problem of the spotify section.
+func (c *Config) ValidateSpotify() error {
+	var errs []error
+
+	if c.Spotify.ClientID == "" {
+		errs = append(errs, missing("spotify.client_id", "CLIENT_ID_ENV"))
+	}
+	if c.Spotify.ClientSecret == "" {
+		errs = append(errs, missing("spotify.client_secret", "CLIENT_SECRET_ENV"))
+	}
+	if c.Spotify.RefreshToken == "" {
+		errs = append(errs, missing("spotify.refresh_token", "REFRESH_TOKEN_ENV"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateIdeas reports every problem of the ideas section.
+func (c *Config) ValidateIdeas() error {
+	var errs []error
+
+	if c.Ideas.Layout != IdeasLayoutSingle && c.Ideas.Layout != IdeasLayoutObjects {
+		errs = append(errs, fmt.Errorf("ideas.layout: unknown layout %q, must be %q or %q", c.Ideas.Layout, IdeasLayoutSingle, IdeasLayoutObjects))
+	}
+
+	switch c.Ideas.Backend {
+	case IdeasBackendGCS:
+		if c.Ideas.Bucket == "" {
+			errs = append(errs, missing("ideas.bucket", "GCS_BUCKET_ENV"))
+		}
+		if c.Ideas.Layout == IdeasLayoutSingle && c.Ideas.Object == "" {
+			errs = append(errs, missing("ideas.object", "GCS_OBJECT_ENV"))
+		}
+	case IdeasBackendFilesystem:
+		if c.Ideas.Path == "" {
+			errs = append(errs, missing("ideas.path", "IDEAS_PATH_ENV"))
+		}
+	case IdeasBackendSQLite:
+		if c.Ideas.Path == "" {
+			errs = append(errs, missing("ideas.path", "IDEAS_PATH_ENV"))
+		}
+		if c.Ideas.Layout == IdeasLayoutObjects {
+			errs = append(errs, fmt.Errorf("ideas.layout: the %q backend doesn't support the %q layout", IdeasBackendSQLite, IdeasLayoutObjects))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("ideas.backend: unknown backend %q, must be %q, %q or %q", c.Ideas.Backend, IdeasBackendGCS, IdeasBackendFilesystem, IdeasBackendSQLite))
+	}
+
+	if c.Ideas.MaxRevisions <= 0 {
+		errs = append(errs, errors.New("ideas.max_revisions must be positive"))
+	}
+	if c.Ideas.CacheTTL < 0 {
+		errs = append(errs, errors.New("ideas.cache_ttl can't be negative"))
+	}
+	if c.Ideas.SchedulerInterval <= 0 {
+		errs = append(errs, errors.New("ideas.scheduler_interval must be positive"))
+	}
+	if c.Ideas.TrashRetention <= 0 {
+		errs = append(errs, errors.New("ideas.trash_retention must be positive"))
+	}
+	if c.Ideas.PurgeInterval <= 0 {
+		errs = append(errs, errors.New("ideas.purge_interval must be positive"))
+	}
+	if c.Ideas.CompactionInterval <= 0 {
+		errs = append(errs, errors.New("ideas.compaction_interval must be positive"))
+	}
+	errs = append(errs, validateURL("ideas.feed.link", c.Ideas.Feed.Link)...)
+	if c.Ideas.Feed.BaseURL != "" {
+		errs = append(errs, validateURL("ideas.feed.base_url", c.Ideas.Feed.BaseURL)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateListening reports every problem of the listening section.
+func (c *Config) ValidateListening() error {
+	var errs []error
+
+	if !c.Spotify.Enabled {
+		errs = append(errs, errors.New("listening.enabled requires spotify.enabled"))
+	}
+	if (c.Listening.Bucket == "") == (c.Listening.Dir == "") {
+		errs = append(errs, errors.New("exactly one of listening.bucket (LISTENING_BUCKET_ENV) and listening.dir (LISTENING_DIR_ENV) must be set"))
+	}
+	if c.Listening.SnapshotInterval <= 0 {
+		errs = append(errs, errors.New("listening.snapshot_interval must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func missing(key string, env string) error {
+	return fmt.Errorf("%s is required, set it in the config file or with %s", key, env)
+}
+
+func validateURL(key string, rawURL string) []error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return []error{fmt.Errorf("%s must be an absolute URL, got %q", key, rawURL)}
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv points to the config file, if --config isn't given.
+const ConfigFileEnv = "CONFIG_FILE_ENV"
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	secretType   = reflect.TypeFor[Secret]()
+)
+
+// Options are command-line flags that aren't part of the config itself.
+type Options struct {
+	// ConfigFile is the YAML config file. Optional.
+	ConfigFile string
+
+	// PrintConfig prints the effective config, with secrets redacted, and exits.
+	PrintConfig bool
+}
+
+// field is a leaf of the Config struct, e.g. Spotify.ClientID.
+type field struct {
+	// key is the full path in the config file, e.g. "spotify.client_id".
+	key string
+
+	env          string
+	defaultValue string
+	value        reflect.Value
+}
+
+// Load builds the config from defaults, the config file, environment variables
+// and command-line flags, in that order of precedence. All problems found are
+// reported at once. Unless flags can't be parsed, the config and options are
+// returned even with problems, so --print-config can show a broken config.
+func Load(args []string) (*Config, *Options, error) {
+	cfg := &Config{}
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), "")
+
+	options, flagValues, err := parseFlags(args, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if f.defaultValue != "" {
+			errs = append(errs, f.set(f.defaultValue, "default"))
+		}
+	}
+
+	if options.ConfigFile == "" {
+		options.ConfigFile = os.Getenv(ConfigFileEnv)
+	}
+	if options.ConfigFile != "" {
+		errs = append(errs, loadFile(options.ConfigFile, fields))
+	}
+
+	for _, f := range fields {
+		errs = append(errs, f.loadEnv())
+	}
+
+	for _, f := range fields {
+		if raw, exists := flagValues[f.key]; exists {
+			errs = append(errs, f.set(raw, "flag --"+flagName(f.key)))
+		}
+	}
+
+	errs = append(errs, cfg.validate())
+	return cfg, options, errors.Join(errs...)
+}
+
+func collectFields(v reflect.Value, prefix string) []*field {
+	var fields []*field
+
+	t := v.Type()
+	for i := range t.NumField() {
+		structField := t.Field(i)
+		key := prefix + structField.Tag.Get("yaml")
+
+		if structField.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(v.Field(i), key+".")...)
+			continue
+		}
+
+		fields = append(fields, &field{
+			key:          key,
+			env:          structField.Tag.Get("env"),
+			defaultValue: structField.Tag.Get("default"),
+			value:        v.Field(i),
+		})
+	}
+
+	return fields
+}
+
+func parseFlags(args []string, fields []*field) (*Options, map[string]string, error) {
+	options := &Options{}
+	flagValues := make(map[string]string)
+
+	flagSet := flag.NewFlagSet("website-backend", flag.ContinueOnError)
+	flagSet.StringVar(&options.ConfigFile, "config", "", "path to the YAML config file (env "+ConfigFileEnv+")")
+	flagSet.BoolVar(&options.PrintConfig, "print-config", false, "print the effective config, with secrets redacted, and exit")
+
+	for _, f := range fields {
+		usage := "env " + f.env
+		if f.defaultValue != "" {
+			usage += ", default " + f.defaultValue
+		}
+		flagSet.Var(&flagValue{key: f.key, values: flagValues, isBool: f.value.Kind() == reflect.Bool}, flagName(f.key), usage)
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	return options, flagValues, nil
+}
+
+// flagName turns a config key into a flag name, e.g. "spotify.client_id"
+// into "spotify.client-id".
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// flagValue records which flags were explicitly set, so they are only applied
+// on top of the config file and environment variables.
+type flagValue struct {
+	key    string
+	values map[string]string
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.key]
+}
+
+func (f *flagValue) Set(raw string) error {
+	f.values[f.key] = raw
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func loadFile(path string, fields []*field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	values := make(map[string]any)
+	flatten(root, "", values)
+
+	var errs []error
+	for _, f := range fields {
+		// Null values, e.g. "client_id:" with nothing after it, leave the
+		// setting unset.
+		if value, exists := values[f.key]; exists {
+			if value != nil {
+				errs = append(errs, f.set(scalar(value), "config file"))
+			}
+			delete(values, f.key)
+		}
+
+		// Secrets can also be read from a file, e.g. "client_secret_file".
+		if path, exists := values[f.key+"_file"]; exists && f.value.Type() == secretType {
+			if path != nil {
+				errs = append(errs, f.setFromFile(scalar(path), "config file"))
+			}
+			delete(values, f.key+"_file")
+		}
+	}
+
+	// Typos would otherwise be silently ignored.
+	for key := range values {
+		errs = append(errs, fmt.Errorf("config file: unknown key %s", key))
+	}
+	return errors.Join(errs...)
+}
+
+// flatten turns nested maps into dotted keys, e.g. {"spotify": {"client_id": x}}
+// into {"spotify.client_id": x}.
+func flatten(m map[string]any, prefix string, values map[string]any) {
+	for key, value := range m {
+		if nested, ok := value.(map[string]any); ok {
+			flatten(nested, prefix+key+".", values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+// scalar converts a YAML value back to its textual form. Lists are joined
+// with commas, like in environment variables, without their null items.
+func scalar(value any) string {
+	list, ok := value.([]any)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != nil {
+			items = append(items, fmt.Sprint(item))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+func (f *field) loadEnv() error {
+	if f.env == "" {
+		return nil
+	}
+
+	if raw, exists := os.LookupEnv(f.env); exists {
+		return f.set(raw, "env "+f.env)
+	}
+
+	if path, exists := os.LookupEnv(f.env + "_FILE"); exists && f.value.Type() == secretType {
+		return f.setFromFile(path, "env "+f.env+"_FILE")
+	}
+	return nil
+}
+
+func (f *field) setFromFile(path string, source string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s (from %s): failed to read secret file: %v", f.key, source, err)
+	}
+
+	// Editors and secret managers commonly add a trailing newline.
+	return f.set(strings.TrimRight(string(data), "\r\n"), source)
+}
+
+// set parses raw into the field. source is only used in error messages.
+func (f *field) set(raw string, source string) error {
+	var err error
+
+	switch {
+	case f.value.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(raw)
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(raw)
+	case f.value.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(raw)
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.Int:
+		var i int
+		i, err = strconv.Atoi(raw)
+		f.value.SetInt(int64(i))
+	case f.value.Kind() == reflect.Slice && f.value.Type().Elem().Kind() == reflect.String:
+		f.value.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		err = fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s (from %s): %v", f.key, source, err)
+	}
+	return nil
+}
+
+func splitList(raw string) []string {
+	items := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Print writes the config as YAML, with secrets redacted. The output is a
+// valid config file, apart from the redacted secrets.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	printNode(root, reflect.ValueOf(c).Elem())
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func printNode(node *yaml.Node, v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: t.Field(i).Tag.Get("yaml")}
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			nested := &yaml.Node{Kind: yaml.MappingNode}
+			printNode(nested, value)
+			node.Content = append(node.Content, key, nested)
+		case value.Kind() == reflect.Slice:
+			list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for j := range value.Len() {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: value.Index(j).String()})
+			}
+			node.Content = append(node.Content, key, list)
+		default:
+			// Secret and time.Duration implement fmt.Stringer.
+			node.Content = append(node.Content, key, &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(value.Interface())})
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setRequired sets the settings without defaults, so that tests only fail
+// because of what they're about.
+func setRequired(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("CLIENT_ID_ENV", "id")
+	t.Setenv("CLIENT_SECRET_ENV", "secret")
+	t.Setenv("REFRESH_TOKEN_ENV", "token")
+	t.Setenv("GCS_BUCKET_ENV", "bucket")
+	t.Setenv("GCS_OBJECT_ENV", "ideas.json")
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", `
+http_address: ":1000"
+cors:
+  allowed_origins: [https://file.example]
+listening:
+  snapshot_interval: 12h
+`)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		get  func(*Config) any
+		want any
+	}{
+		{
+			name: "default",
+			args: []string{"--config", configFile},
+			get:  func(c *Config) any { return c.RequestTimeout },
+			want: 10 * time.Second,
+		},
+		{
+			name: "file over default",
+			args: []string{"--config", configFile},
+			get:  func(c *Config) any { return c.Listening.SnapshotInterval },
+			want: 12 * time.Hour,
+		},
+		{
+			name: "env over file",
+			env:  map[string]string{"HTTP_ADDRESS_ENV": ":2000"},
+			args: []string{"--config", configFile},
+			get:  func(c *Config) any { return c.HTTPAddress },
+			want: ":2000",
+		},
+		{
+			name: "flag over env",
+			env:  map[string]string{"HTTP_ADDRESS_ENV": ":2000"},
+			args: []string{"--config", configFile, "--http-address", ":3000"},
+			get:  func(c *Config) any { return c.HTTPAddress },
+			want: ":3000",
+		},
+		{
+			name: "config file from env",
+			env:  map[string]string{ConfigFileEnv: configFile},
+			get:  func(c *Config) any { return c.HTTPAddress },
+			want: ":1000",
+		},
+		{
+			name: "env lists",
+			env:  map[string]string{"CORS_ALLOWED_ORIGINS_ENV": "https://a.example, https://b.example,"},
+			args: []string{"--config", configFile},
+			get:  func(c *Config) any { return c.CORS.AllowedOrigins },
+			want: []string{"https://a.example", "https://b.example"},
+		},
+		{
+			name: "file lists",
+			args: []string{"--config", configFile},
+			get:  func(c *Config) any { return c.CORS.AllowedOrigins },
+			want: []string{"https://file.example"},
+		},
+		{
+			name: "bool flag without value",
+			args: []string{"--cors.allowed-origins", "https://a.example", "--cors.allow-credentials"},
+			get:  func(c *Config) any { return c.CORS.AllowCredentials },
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, _, err := Load(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := tt.get(cfg)
+			if list, ok := got.([]string); ok {
+				if !slices.Equal(list, tt.want.([]string)) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	setRequired(t)
+
+	apiKeyFile := writeFile(t, "api-key", "from-file\n")
+	configFile := writeFile(t, "config.yaml", `
+now_playing:
+  providers: [lastfm]
+lastfm:
+  user: me
+  api_key_file: `+apiKeyFile+"\n")
+
+	cfg, _, err := Load([]string{"--config", configFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LastFM.APIKey.Value() != "from-file" {
+		t.Errorf("lastfm.api_key = %q, want the file content without its newline", cfg.LastFM.APIKey.Value())
+	}
+
+	t.Setenv("LASTFM_API_KEY_ENV_FILE", writeFile(t, "env-api-key", "from-env-file"))
+	cfg, _, err = Load([]string{"--config", configFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LastFM.APIKey.Value() != "from-env-file" {
+		t.Errorf("lastfm.api_key = %q, want the env file to win over the config file", cfg.LastFM.APIKey.Value())
+	}
+}
+
+func TestLoadNullsLeaveSettingsUnset(t *testing.T) {
+	setRequired(t)
+
+	configFile := writeFile(t, "config.yaml", `
+http_address:
+lastfm:
+  user: ~
+cors:
+  allowed_origins: [https://a.example, null]
+`)
+
+	cfg, _, err := Load([]string{"--config", configFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTPAddress != ":8080" {
+		t.Errorf("http_address = %q, want the default", cfg.HTTPAddress)
+	}
+	if cfg.LastFM.User != "" {
+		t.Errorf("lastfm.user = %q, want it unset", cfg.LastFM.User)
+	}
+	if !slices.Equal(cfg.CORS.AllowedOrigins, []string{"https://a.example"}) {
+		t.Errorf("cors.allowed_origins = %v, want null items dropped", cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setRequired(t)
+
+	configFile := writeFile(t, "config.yaml", "cors:\n  allow_credentials: maybe\nunknown_key: 1\n")
+	t.Setenv("REQUEST_TIMEOUT_ENV", "0s")
+
+	cfg, options, err := Load([]string{"--config", configFile, "--print-config"})
+	if err == nil {
+		t.Fatal("Load() succeeded, want an error")
+	}
+	for _, problem := range []string{"cors.allow_credentials (from config file)", "unknown key unknown_key", "request_timeout must be positive"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Load() error = %q, want it to mention %q", err, problem)
+		}
+	}
+
+	// Broken configs can still be printed.
+	if cfg == nil || options == nil || !options.PrintConfig {
+		t.Errorf("Load() = %v, %+v, want the config and options with problems", cfg, options)
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "log/slog"
+
+// Redacted replaces secrets wherever the config is printed.
+const Redacted = "[REDACTED]"
+
+// Secret is a string that must never be printed, e.g. tokens and passwords.
+// Use Value to get the actual secret.
+type Secret string
+
+// Value returns the actual secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String redacts the secret, so it's safe to use with fmt.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// LogValue redacts the secret, so it's safe to use with slog.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/health"
+)
+
+// Check tells whether a dependency of the server, e.g. Spotify or GCS, is
+// usable. It returns nil when it is.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+
+	// Optional checks are reported as degraded when they fail, without
+	// failing readiness.
+	Optional bool
+}
+
+// Checker runs readiness checks and caches their results, so frequent probes
+// don't hammer upstreams.
+type Checker struct {
+	checks  []*cachedCheck
+	ttl     time.Duration
+	timeout time.Duration
+
+	// shuttingDown makes readiness fail while the server drains requests.
+	shuttingDown atomic.Bool
+}
+
+type cachedCheck struct {
+	check Check
+
+	// lock serializes runs of the check, so concurrent probes share a result.
+	lock   sync.Mutex
+	result *health.Check
+}
+
+func NewChecker(cfg config.Health, checks ...Check) *Checker {
+	c := &Checker{
+		ttl:     cfg.CacheTTL,
+		timeout: cfg.CheckTimeout,
+	}
+	for _, check := range checks {
+		c.checks = append(c.checks, &cachedCheck{check: check})
+	}
+	return c
+}
+
+// SetShuttingDown makes readiness fail from now on.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// HandleHealthz receives an HTTP request and tells that the process is alive.
+// It doesn't check dependencies: an upstream outage shouldn't restart the server.
+func (c *Checker) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &health.HealthResponse{Status: health.StatusOK})
+}
+
+// HandleReadyz receives an HTTP request and returns the result of every check
+// in ReadinessResponse format. It fails with 503 if any check that isn't
+// optional fails or the server is shutting down. Failed optional checks only
+// make it degraded.
+func (c *Checker) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	response := &health.ReadinessResponse{
+		Status: health.StatusOK,
+		Checks: c.run(r.Context()),
+	}
+
+	for _, check := range response.Checks {
+		if check.Status == health.StatusDegraded && response.Status == health.StatusOK {
+			response.Status = health.StatusDegraded
+		}
+		if check.Status == health.StatusFail {
+			response.Status = health.StatusFail
+		}
+	}
+	if c.shuttingDown.Load() {
+		response.Status = health.StatusShuttingDown
+	}
+
+	statusCode := http.StatusOK
+	if response.Status != health.StatusOK && response.Status != health.StatusDegraded {
+		statusCode = http.StatusServiceUnavailable
+	}
+	writeJSON(w, statusCode, response)
+}
+
+// run runs all checks concurrently, reusing results younger than the cache TTL.
+func (c *Checker) run(ctx context.Context) []*health.Check {
+	results := make([]*health.Check, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *Checker) runCheck(ctx context.Context, check *cachedCheck) *health.Check {
+	check.lock.Lock()
+	defer check.lock.Unlock()
+
+	if check.result != nil && time.Since(check.result.CheckedAt) < c.ttl {
+		return check.result
+	}
+
+	// Checks outlive the probe that triggered them, so a cancelled probe
+	// doesn't cache a failure.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.check.Check(ctx)
+	result := &health.Check{
+		Name:      check.check.Name,
+		Status:    health.StatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+	}
+
+	// Errors might leak details of the deployment, so they're only logged.
+	if err != nil {
+		slog.WarnContext(ctx, "readiness check failed", "check", check.check.Name, "err", err)
+		result.Status = health.StatusFail
+		if check.check.Optional {
+			result.Status = health.StatusDegraded
+		}
+	}
+
+	check.result = result
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		slog.Error("failed to encode json response", "err", err)
+	}
+}
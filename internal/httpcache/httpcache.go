@@ -0,0 +1,84 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag returns a strong ETag derived from the response body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ServeJSON writes body as a JSON response with ETag and Cache-Control headers.
+// If the client already has the same representation, 304 Not Modified is
+// returned instead.
+func ServeJSON(w http.ResponseWriter, r *http.Request, body []byte, cacheControl string) {
+	ServeJSONModified(w, r, body, cacheControl, time.Time{})
+}
+
+// ServeJSONModified works like ServeJSON, but also sets Last-Modified, unless
+// it's zero. Clients that don't send If-None-Match can then revalidate with
+// If-Modified-Since.
+func ServeJSONModified(w http.ResponseWriter, r *http.Request, body []byte, cacheControl string, lastModified time.Time) {
+	Serve(w, r, body, "application/json", cacheControl, lastModified)
+}
+
+// Serve works like ServeJSONModified, for any content type.
+func Serve(w http.ResponseWriter, r *http.Request, body []byte, contentType string, cacheControl string, lastModified time.Time) {
+	etag := ETag(body)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// isNotModified evaluates conditional headers as RFC 9110 says: If-Modified-Since
+// is ignored when If-None-Match is present.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Header.Get("If-None-Match") != "" {
+		return matchesETag(r.Header.Get("If-None-Match"), etag)
+	}
+
+	if lastModified.IsZero() || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have a resolution of one second.
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
+
+// matchesETag reports whether an If-None-Match header matches etag, using the
+// weak comparison required by RFC 9110.
+func matchesETag(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
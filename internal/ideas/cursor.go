@@ -0,0 +1,103 @@
+package ideas
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursor points at the last idea of a page. The next page starts right after
+// it, so posting ideas doesn't shift pages like offsets do.
+type cursor struct {
+	time time.Time
+	id   string
+}
+
+// encodeCursor returns the opaque cursor of the page that ends with idea.
+func encodeCursor(idea *ideas.Idea) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d.%s", idea.Time.UnixNano(), idea.ID))
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	nanos, id, found := strings.Cut(string(data), ".")
+	if !found || id == "" {
+		return nil, errInvalidCursor
+	}
+
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &cursor{time: time.Unix(0, unixNano), id: id}, nil
+}
+
+// start returns the position of the first idea after c in allIdeas, newest
+// first. If the idea of c is gone, the next page starts at the first older one.
+func (c *cursor) start(allIdeas []*ideas.Idea) int {
+	for i, idea := range allIdeas {
+		if idea.ID == c.id {
+			return i + 1
+		}
+		if idea.Time.Before(c.time) {
+			return i
+		}
+	}
+	return len(allIdeas)
+}
+
+// paginate returns the page of allIdeas requested by req, starting at its
+// cursor or offset, and the cursor of the next page, if there's one.
+func paginate(allIdeas []*ideas.Idea, req *ideas.GetIdeasRequest) ([]*ideas.Idea, string, error) {
+	start := req.Offset
+	if req.Cursor != "" {
+		c, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		start = c.start(allIdeas)
+	}
+
+	pageIdeas := page(allIdeas, &ideas.GetIdeasRequest{Offset: start, Limit: req.Limit})
+	if len(pageIdeas) == 0 || start+len(pageIdeas) >= len(allIdeas) {
+		return pageIdeas, "", nil
+	}
+	return pageIdeas, encodeCursor(pageIdeas[len(pageIdeas)-1]), nil
+}
+
+// setLinkHeader links to the first and next pages, as RFC 8288 says. Links
+// keep the filters of the request.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Del("cursor")
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(r, query))}
+	if nextCursor != "" {
+		query.Set("cursor", nextCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(r, query)))
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageURL(r *http.Request, query url.Values) string {
+	if len(query) == 0 {
+		return r.URL.EscapedPath()
+	}
+	return r.URL.EscapedPath() + "?" + query.Encode()
+}
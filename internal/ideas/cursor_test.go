@@ -0,0 +1,196 @@
+package ideas
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// newestFirst returns n ideas, a minute apart, newest first, with IDs from
+// "idea-<n-1>" down to "idea-0".
+func newestFirst(n int) []*ideas.Idea {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	allIdeas := make([]*ideas.Idea, n)
+	for i := range allIdeas {
+		j := n - 1 - i
+		allIdeas[i] = &ideas.Idea{ID: fmt.Sprintf("idea-%d", j), Time: start.Add(time.Duration(j) * time.Minute)}
+	}
+	return allIdeas
+}
+
+func ids(allIdeas []*ideas.Idea) []string {
+	result := make([]string, 0, len(allIdeas))
+	for _, idea := range allIdeas {
+		result = append(result, idea.ID)
+	}
+	return result
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	idea := &ideas.Idea{ID: "a.b-c", Time: time.Date(2026, 10, 19, 7, 0, 0, 123, time.UTC)}
+
+	c, err := decodeCursor(encodeCursor(idea))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.id != idea.ID || !c.time.Equal(idea.Time) {
+		t.Errorf("decodeCursor(encodeCursor()) = %+v, want the time and ID of %+v", c, idea)
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	tests := map[string]string{
+		"not base64":   "!!!",
+		"no separator": encode("123"),
+		"no ID":        encode("123."),
+		"no time":      encode(".id"),
+		"invalid time": encode("yesterday.id"),
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decodeCursor(s); !errors.Is(err, errInvalidCursor) {
+				t.Errorf("decodeCursor(%q) error = %v, want errInvalidCursor", s, err)
+			}
+		})
+	}
+}
+
+// encode encodes s like cursors are, to build broken ones.
+func encode(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestPaginate(t *testing.T) {
+	allIdeas := newestFirst(5)
+
+	tests := []struct {
+		name string
+		req  *ideas.GetIdeasRequest
+
+		// after changes allIdeas between the first page and the next one.
+		after func([]*ideas.Idea) []*ideas.Idea
+
+		first []string
+		next  []string
+	}{
+		{
+			name:  "walks every page",
+			req:   &ideas.GetIdeasRequest{Limit: 2},
+			first: []string{"idea-4", "idea-3"},
+			next:  []string{"idea-2", "idea-1"},
+		},
+		{
+			name: "new ideas don't shift pages",
+			req:  &ideas.GetIdeasRequest{Limit: 2},
+			after: func(allIdeas []*ideas.Idea) []*ideas.Idea {
+				newer := &ideas.Idea{ID: "idea-5", Time: allIdeas[0].Time.Add(time.Minute)}
+				return append([]*ideas.Idea{newer}, allIdeas...)
+			},
+			first: []string{"idea-4", "idea-3"},
+			next:  []string{"idea-2", "idea-1"},
+		},
+		{
+			name: "resumes after a deleted idea",
+			req:  &ideas.GetIdeasRequest{Limit: 2},
+			after: func(allIdeas []*ideas.Idea) []*ideas.Idea {
+				return slices.DeleteFunc(slices.Clone(allIdeas), func(idea *ideas.Idea) bool {
+					return idea.ID == "idea-3"
+				})
+			},
+			first: []string{"idea-4", "idea-3"},
+			next:  []string{"idea-2", "idea-1"},
+		},
+		{
+			name:  "offset",
+			req:   &ideas.GetIdeasRequest{Offset: 1, Limit: 3},
+			first: []string{"idea-3", "idea-2", "idea-1"},
+			next:  []string{"idea-0"},
+		},
+		{
+			name:  "last page has no cursor",
+			req:   &ideas.GetIdeasRequest{Limit: 5},
+			first: []string{"idea-4", "idea-3", "idea-2", "idea-1", "idea-0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, cursor, err := paginate(allIdeas, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids(first), tt.first) {
+				t.Errorf("first page = %v, want %v", ids(first), tt.first)
+			}
+			if (cursor == "") != (tt.next == nil) {
+				t.Fatalf("cursor = %q, want one only if there's a next page", cursor)
+			}
+			if cursor == "" {
+				return
+			}
+
+			current := allIdeas
+			if tt.after != nil {
+				current = tt.after(allIdeas)
+			}
+			next, _, err := paginate(current, &ideas.GetIdeasRequest{Cursor: cursor, Limit: tt.req.Limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids(next), tt.next) {
+				t.Errorf("next page = %v, want %v", ids(next), tt.next)
+			}
+		})
+	}
+}
+
+func TestPaginateRejectsInvalidCursors(t *testing.T) {
+	_, _, err := paginate(newestFirst(3), &ideas.GetIdeasRequest{Cursor: "!!!", Limit: 2})
+	if !errors.Is(err, errInvalidCursor) {
+		t.Errorf("paginate() error = %v, want errInvalidCursor", err)
+	}
+}
+
+func TestSetLinkHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		nextCursor string
+		want       string
+	}{
+		{
+			name: "first page only",
+			url:  "/ideas?limit=10",
+			want: `</ideas?limit=10>; rel="first"`,
+		},
+		{
+			name:       "keeps filters, drops offsets and cursors",
+			url:        "/ideas?tag=go&offset=20&cursor=old&limit=10",
+			nextCursor: "new",
+			want:       `</ideas?limit=10&tag=go>; rel="first", </ideas?cursor=new&limit=10&tag=go>; rel="next"`,
+		},
+		{
+			name: "no query",
+			url:  "/ideas",
+			want: `</ideas>; rel="first"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			setLinkHeader(w, httptest.NewRequest("GET", tt.url, nil), tt.nextCursor)
+
+			if got := w.Header().Get("Link"); got != tt.want {
+				t.Errorf("Link = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+const DraftsPath = "/ideas/drafts"
+
+// HandleGetDrafts receives an HTTP request and returns a page of draft and
+// scheduled ideas in GetIdeasResponse format. They're private, so responses
+// are never cached.
+func (s *IdeasClient) HandleGetDrafts(w http.ResponseWriter, r *http.Request) {
+	s.servePrivateIdeas(w, r, func(snapshot *ideasSnapshot) []*ideas.Idea {
+		return snapshot.drafts
+	})
+}
+
+// servePrivateIdeas serves a page of ideas that only authenticated callers
+// can see, in GetIdeasResponse format.
+func (s *IdeasClient) servePrivateIdeas(w http.ResponseWriter, r *http.Request, list func(*ideasSnapshot) []*ideas.Idea) {
+	snapshot, err := s.getIdeas(r.Context())
+	if err != nil {
+		http.Error(w, "failed to fetch my ideas", http.StatusInternalServerError)
+		return
+	}
+
+	req, err := parseGetIdeasRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allIdeas := list(snapshot)
+	pageIdeas, nextCursor, err := paginate(allIdeas, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	setLinkHeader(w, r, nextCursor)
+	writePrivateJSON(w, &ideas.GetIdeasResponse{
+		Ideas:      pageIdeas,
+		NextCursor: nextCursor,
+		Total:      len(allIdeas),
+	})
+}
+
+// RunScheduler publishes scheduled ideas when their time comes, until ctx is
+// done.
+func (s *IdeasClient) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(s.schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.publishScheduled(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishScheduled publishes the scheduled ideas whose time is up to now.
+func (s *IdeasClient) publishScheduled(ctx context.Context, now time.Time) {
+	snapshot, err := s.getIdeas(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch scheduled ideas", "err", err)
+		return
+	}
+
+	for _, idea := range snapshot.drafts {
+		if idea.Status != ideas.StatusScheduled || idea.Time.After(now) {
+			continue
+		}
+
+		published, err := s.ideasRepo.UpdateIdea(ctx, idea.ID, func(stored *ideas.Idea) error {
+			if stored.Status != ideas.StatusScheduled || stored.Time.After(now) || !stored.DeletedAt.IsZero() {
+				return ErrIdeaConflict
+			}
+			stored.Status = ideas.StatusPublished
+			return nil
+		})
+		if errors.Is(err, ErrIdeaNotFound) || errors.Is(err, ErrIdeaConflict) {
+			// Another replica published, changed or purged it since the
+			// snapshot was taken, and it's the one that emits events.
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to publish scheduled idea", "idea_id", idea.ID, "err", err)
+			continue
+		}
+
+		s.applyIdea(published)
+		s.emit(ctx, &Event{Type: EventPublished, Idea: published})
+	}
+}
@@ -0,0 +1,164 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/cache"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// newTestClient returns a client of repo whose snapshots don't expire during
+// tests, and the events it emits.
+func newTestClient(repo IdeasRepository) (*IdeasClient, *[]string) {
+	client := &IdeasClient{
+		ideasRepo:      repo,
+		cache:          cache.NewTTLCache[*ideasSnapshot]("ideas", time.Hour, 1),
+		trashRetention: 30 * 24 * time.Hour,
+	}
+
+	var events []string
+	client.Subscribe(func(_ context.Context, event *Event) {
+		events = append(events, string(event.Type)+" "+event.Idea.Idea)
+	})
+	return client, &events
+}
+
+// postIdea posts an idea with status and time to repo.
+func postIdea(t *testing.T, repo IdeasRepository, text, status string, publishAt time.Time) *ideas.Idea {
+	t.Helper()
+
+	response, err := repo.PostIdea(context.Background(), &ideas.PostIdeaRequest{
+		Idea:      text,
+		Status:    status,
+		PublishAt: publishAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.Idea
+}
+
+// snapshotTexts returns the texts of the published ideas and the drafts of
+// the snapshot of client.
+func snapshotTexts(t *testing.T, client *IdeasClient) (published, drafts []string) {
+	t.Helper()
+
+	snapshot, err := client.getIdeas(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ideaTexts(snapshot.ideas), ideaTexts(snapshot.drafts)
+}
+
+func TestPublishScheduled(t *testing.T) {
+	repo := newTestSQLite(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	postIdea(t, repo, "public", ideas.StatusPublished, now.Add(-2*time.Hour))
+	postIdea(t, repo, "draft", ideas.StatusDraft, now.Add(-time.Hour))
+	postIdea(t, repo, "due", ideas.StatusScheduled, now)
+	postIdea(t, repo, "later", ideas.StatusScheduled, now.Add(time.Hour))
+
+	client, events := newTestClient(repo)
+	client.publishScheduled(context.Background(), now)
+
+	// Ideas are published at their time, drafts never.
+	published, drafts := snapshotTexts(t, client)
+	if want := []string{"due", "public"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published ideas = %q, want %q", published, want)
+	}
+	if want := []string{"later", "draft"}; !reflect.DeepEqual(drafts, want) {
+		t.Errorf("drafts = %q, want %q", drafts, want)
+	}
+	if want := []string{"idea.published due"}; !reflect.DeepEqual(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+
+	// The repository has them published too, for other replicas.
+	client.cache.Clear()
+	if published, _ := snapshotTexts(t, client); !reflect.DeepEqual(published, []string{"due", "public"}) {
+		t.Errorf("published ideas after reloading = %q, want the due one", published)
+	}
+
+	client.publishScheduled(context.Background(), now.Add(time.Hour))
+	published, drafts = snapshotTexts(t, client)
+	if want := []string{"later", "due", "public"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published ideas an hour later = %q, want %q", published, want)
+	}
+	if want := []string{"draft"}; !reflect.DeepEqual(drafts, want) {
+		t.Errorf("drafts an hour later = %q, want %q", drafts, want)
+	}
+}
+
+func TestPublishScheduledByAnotherReplica(t *testing.T) {
+	repo := newTestSQLite(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := postIdea(t, repo, "due", ideas.StatusScheduled, now)
+
+	client, events := newTestClient(repo)
+	snapshotTexts(t, client)
+
+	// Another replica publishes it after the snapshot was taken.
+	_, err := repo.UpdateIdea(context.Background(), due.ID, func(idea *ideas.Idea) error {
+		idea.Status = ideas.StatusPublished
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.publishScheduled(context.Background(), now)
+	if len(*events) != 0 {
+		t.Errorf("events = %q, want none, as the other replica emits them", *events)
+	}
+}
+
+// failingRepository fails to update the ideas with the IDs in failing.
+type failingRepository struct {
+	IdeasRepository
+	failing map[string]bool
+}
+
+func (r *failingRepository) UpdateIdea(ctx context.Context, id string, update func(*ideas.Idea) error) (*ideas.Idea, error) {
+	if r.failing[id] {
+		return nil, errors.New("storage is down")
+	}
+	return r.IdeasRepository.UpdateIdea(ctx, id, update)
+}
+
+func TestPublishScheduledFailure(t *testing.T) {
+	sqlite := newTestSQLite(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	failing := postIdea(t, sqlite, "failing", ideas.StatusScheduled, now)
+	postIdea(t, sqlite, "due", ideas.StatusScheduled, now.Add(-time.Hour))
+
+	repo := &failingRepository{IdeasRepository: sqlite, failing: map[string]bool{failing.ID: true}}
+	client, events := newTestClient(repo)
+	client.publishScheduled(context.Background(), now)
+
+	// A failure doesn't stop the other ideas from being published, and the
+	// failed one stays scheduled.
+	published, drafts := snapshotTexts(t, client)
+	if want := []string{"due"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published ideas = %q, want %q", published, want)
+	}
+	if want := []string{"failing"}; !reflect.DeepEqual(drafts, want) {
+		t.Errorf("drafts = %q, want %q", drafts, want)
+	}
+	if want := []string{"idea.published due"}; !reflect.DeepEqual(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+
+	// It's published on the next run once the repository is back.
+	delete(repo.failing, failing.ID)
+	client.publishScheduled(context.Background(), now)
+	if published, _ := snapshotTexts(t, client); !reflect.DeepEqual(published, []string{"failing", "due"}) {
+		t.Errorf("published ideas after retrying = %q, want the failed one too", published)
+	}
+	if want := []string{"idea.published due", "idea.published failing"}; !reflect.DeepEqual(*events, want) {
+		t.Errorf("events after retrying = %q, want %q", *events, want)
+	}
+}
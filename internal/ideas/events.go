@@ -0,0 +1,48 @@
+package ideas
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+type EventType string
+
+const (
+	// EventPublished is emitted when an idea becomes public, either when it's
+	// posted or when its scheduled time comes.
+	EventPublished EventType = "idea.published"
+
+	// EventDeleted, EventRestored and EventPurged are emitted when an idea is
+	// moved to the trash, restored from it, or purged for good.
+	EventDeleted  EventType = "idea.deleted"
+	EventRestored EventType = "idea.restored"
+	EventPurged   EventType = "idea.purged"
+)
+
+// Event tells observers that something happened to an idea.
+type Event struct {
+	Type EventType
+	Idea *ideas.Idea
+}
+
+// Observer is notified of events. Observers run synchronously, so they
+// must be quick and must not modify the idea.
+type Observer func(ctx context.Context, event *Event)
+
+// Subscribe adds an observer of events. It must be called before the client
+// serves requests, as observers aren't protected by a lock.
+func (s *IdeasClient) Subscribe(observer Observer) {
+	s.observers = append(s.observers, observer)
+}
+
+func (s *IdeasClient) emit(ctx context.Context, event *Event) {
+	for _, observer := range s.observers {
+		observer(ctx, event)
+	}
+}
+
+func logEvent(ctx context.Context, event *Event) {
+	slog.InfoContext(ctx, "idea event", "type", event.Type, "idea_id", event.Idea.ID)
+}
@@ -0,0 +1,247 @@
+package ideas
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jaehnri/website-backend/internal/httpcache"
+	"github.com/jaehnri/website-backend/pkg/feed"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+const (
+	RSSPath      = "/ideas/feed.xml"
+	AtomPath     = "/ideas/atom.xml"
+	JSONFeedPath = "/ideas/feed.json"
+
+	// FeedLength is how many of the newest ideas are in feeds.
+	FeedLength = DefaultLimit
+
+	// feedTitleLength is the maximum length of item titles, in runes. Ideas
+	// have no titles, so they're the start of the idea.
+	feedTitleLength = 80
+)
+
+// HandleRSS receives an HTTP request and returns the newest ideas as an RSS 2.0 feed.
+func (s *IdeasClient) HandleRSS(w http.ResponseWriter, r *http.Request) {
+	s.serveFeed(w, r, "application/rss+xml; charset=utf-8", func(f *ideaFeed) ([]byte, error) {
+		return encodeXML(s.rss(r, f))
+	})
+}
+
+// HandleAtom receives an HTTP request and returns the newest ideas as an Atom feed.
+func (s *IdeasClient) HandleAtom(w http.ResponseWriter, r *http.Request) {
+	s.serveFeed(w, r, "application/atom+xml; charset=utf-8", func(f *ideaFeed) ([]byte, error) {
+		return encodeXML(s.atom(r, f))
+	})
+}
+
+// HandleJSONFeed receives an HTTP request and returns the newest ideas as a JSON Feed 1.1.
+func (s *IdeasClient) HandleJSONFeed(w http.ResponseWriter, r *http.Request) {
+	s.serveFeed(w, r, "application/feed+json; charset=utf-8", func(f *ideaFeed) ([]byte, error) {
+		return json.Marshal(s.jsonFeed(r, f))
+	})
+}
+
+// ideaFeed is the content of a feed: all ideas, or the ones with a tag.
+type ideaFeed struct {
+	// id identifies the feed in Atom, so it must differ between tags.
+	id    string
+	title string
+
+	// ideas are the newest ideas of the feed, rendered as HTML.
+	ideas []*ideas.Idea
+
+	// lastModified is when the published ideas last changed.
+	lastModified time.Time
+}
+
+// serveFeed encodes the feed and serves it with an ETag and Last-Modified, so
+// feed readers don't refetch unchanged feeds. If the route has a {tag}, only
+// ideas with that tag are in the feed.
+func (s *IdeasClient) serveFeed(w http.ResponseWriter, r *http.Request, contentType string, encode func(*ideaFeed) ([]byte, error)) {
+	snapshot, err := s.getIdeas(r.Context())
+	if err != nil {
+		http.Error(w, "failed to fetch my ideas", http.StatusInternalServerError)
+		return
+	}
+
+	req := &ideas.GetIdeasRequest{Offset: 0, Limit: FeedLength}
+	id := s.feedMetadata.Link
+	title := s.feedMetadata.Title
+	if tag := r.PathValue("tag"); tag != "" {
+		var ok bool
+		req.Tag, ok = normalizeTag(tag)
+		if !ok {
+			http.Error(w, "invalid tag", http.StatusNotFound)
+			return
+		}
+		id += "?tag=" + url.QueryEscape(req.Tag)
+		title += " #" + req.Tag
+	}
+
+	// Feed readers show HTML, so ideas are always rendered.
+	feedIdeas, err := renderIdeas(page(filterIdeas(snapshot, req), req))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to render ideas", "err", err)
+		http.Error(w, "failed to render ideas", http.StatusInternalServerError)
+		return
+	}
+
+	f := &ideaFeed{
+		id:           id,
+		title:        title,
+		ideas:        feedIdeas,
+		lastModified: snapshot.lastModified,
+	}
+
+	body, err := encode(f)
+	if err != nil {
+		http.Error(w, "failed to encode feed", http.StatusInternalServerError)
+		return
+	}
+
+	httpcache.Serve(w, r, body, contentType, s.cacheControl, f.lastModified)
+}
+
+func (s *IdeasClient) rss(r *http.Request, f *ideaFeed) *feed.RSS {
+	channel := feed.RSSChannel{
+		Title:       f.title,
+		Link:        s.feedMetadata.Link,
+		Description: s.feedMetadata.Description,
+		Self: feed.AtomLink{
+			Href: s.feedURL(r),
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+	}
+	if !f.lastModified.IsZero() {
+		channel.LastBuildDate = f.lastModified.Format(time.RFC1123Z)
+	}
+
+	for _, idea := range f.ideas {
+		channel.Items = append(channel.Items, &feed.RSSItem{
+			Title:       feedTitle(idea),
+			Link:        s.ideaURL(idea),
+			Description: idea.HTML,
+			GUID:        feed.RSSGUID{IsPermaLink: true, Value: s.ideaURL(idea)},
+			PubDate:     idea.Time.Format(time.RFC1123Z),
+		})
+	}
+
+	return &feed.RSS{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	}
+}
+
+func (s *IdeasClient) atom(r *http.Request, f *ideaFeed) *feed.AtomFeed {
+	atom := &feed.AtomFeed{
+		ID:       f.id,
+		Title:    f.title,
+		Subtitle: s.feedMetadata.Description,
+		Updated:  atomTime(f.lastModified),
+		Author:   &feed.AtomPerson{Name: s.feedMetadata.Author},
+		Links: []*feed.AtomLink{
+			{Href: s.feedMetadata.Link, Rel: "alternate", Type: "text/html"},
+			{Href: s.feedURL(r), Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	for _, idea := range f.ideas {
+		atom.Entries = append(atom.Entries, &feed.AtomEntry{
+			ID:        s.ideaURL(idea),
+			Title:     feedTitle(idea),
+			Updated:   atomTime(idea.Time),
+			Published: atomTime(idea.Time),
+			Links:     []*feed.AtomLink{{Href: s.ideaURL(idea), Rel: "alternate", Type: "text/html"}},
+			Content:   feed.AtomContent{Type: "html", Value: idea.HTML},
+		})
+	}
+
+	return atom
+}
+
+func (s *IdeasClient) jsonFeed(r *http.Request, f *ideaFeed) *feed.JSONFeed {
+	jsonFeed := &feed.JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.title,
+		HomePageURL: s.feedMetadata.Link,
+		FeedURL:     s.feedURL(r),
+		Description: s.feedMetadata.Description,
+		Authors:     []*feed.JSONAuthor{{Name: s.feedMetadata.Author}},
+		Items:       []*feed.JSONFeedItem{},
+	}
+
+	for _, idea := range f.ideas {
+		jsonFeed.Items = append(jsonFeed.Items, &feed.JSONFeedItem{
+			ID:            s.ideaURL(idea),
+			URL:           s.ideaURL(idea),
+			Title:         feedTitle(idea),
+			ContentText:   idea.Idea,
+			ContentHTML:   idea.HTML,
+			DatePublished: idea.Time.Format(time.RFC3339),
+		})
+	}
+
+	return jsonFeed
+}
+
+// ideaURL is both the link and the GUID of an idea. It only depends on the
+// ID, so it's stable.
+func (s *IdeasClient) ideaURL(idea *ideas.Idea) string {
+	return s.feedMetadata.Link + "#" + idea.ID
+}
+
+// feedURL returns the public URL of the requested feed, for feeds to link to
+// themselves.
+func (s *IdeasClient) feedURL(r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if s.feedMetadata.BaseURL != "" {
+		return strings.TrimSuffix(s.feedMetadata.BaseURL, "/") + path
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
+
+// feedTitle returns the start of the idea, cut at a word boundary.
+func feedTitle(idea *ideas.Idea) string {
+	text := strings.Join(strings.Fields(idea.Idea), " ")
+	if utf8.RuneCountInString(text) <= feedTitleLength {
+		return text
+	}
+
+	title := string([]rune(text)[:feedTitleLength])
+	if i := strings.LastIndex(title, " "); i > 0 {
+		title = title[:i]
+	}
+	return title + "…"
+}
+
+// atomTime formats t as RFC 3339, using the Unix epoch when there are no ideas
+// yet, as Atom feeds must have an updated date.
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func encodeXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
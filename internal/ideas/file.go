@@ -0,0 +1,368 @@
+package ideas
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/storage"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// IdeasFileClient stores ideas in a local JSON file, with the same format as
+// the GCS object. Useful for self-hosting and local development.
+type IdeasFileClient struct {
+	path string
+
+	// revisionsPath holds the past versions of the file, named after the
+	// UnixNano of their modification time, like GCS generations. No
+	// revisions are kept if it's empty.
+	revisionsPath string
+
+	// maxRevisions is how many past versions are kept. Older ones are deleted.
+	maxRevisions int
+
+	// lock serializes writes, so concurrent posts don't lose ideas.
+	lock sync.Mutex
+}
+
+func NewIdeasFileClient(cfg config.Ideas) *IdeasFileClient {
+	revisionsPath := cfg.RevisionsPath
+	if revisionsPath == "" {
+		revisionsPath = cfg.Path + ".revisions"
+	}
+
+	return &IdeasFileClient{
+		path:          cfg.Path,
+		revisionsPath: revisionsPath,
+		maxRevisions:  cfg.MaxRevisions,
+	}
+}
+
+// GetIdeas fetches all the ideas from the local file.
+func (i *IdeasFileClient) GetIdeas(_ context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error) {
+	data, err := os.ReadFile(i.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// No idea was posted yet.
+		data = []byte("[]")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read ideas file %s: %v", i.path, err)
+	}
+
+	return &ideas.GetIdeasResponse{
+		Ideas: parseIdeas(req, data),
+	}, nil
+}
+
+// CheckStorage stats the file. A missing file is fine, as it's created by the
+// first post.
+func (i *IdeasFileClient) CheckStorage(_ context.Context) error {
+	_, err := os.Stat(i.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (i *IdeasFileClient) PostIdea(_ context.Context, req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	currentContent, err := i.readIdeas()
+	if err != nil {
+		return nil, err
+	}
+
+	idea := idea(req)
+	newContent, err := prependIdea(currentContent, idea)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.writeIdeas(newContent); err != nil {
+		return nil, err
+	}
+
+	return &ideas.PostIdeaResponse{
+		Idea: idea,
+	}, nil
+}
+
+// UpdateIdea applies update to the stored idea with id. The file is only
+// written by this process, so the lock is enough to keep it atomic.
+func (i *IdeasFileClient) UpdateIdea(_ context.Context, id string, update func(*ideas.Idea) error) (*ideas.Idea, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	currentContent, err := i.readIdeas()
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := replaceIdea(currentContent, id, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.writeIdeas(currentContent); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteIdea removes the stored idea with id for good.
+func (i *IdeasFileClient) DeleteIdea(_ context.Context, id string, check func(*ideas.Idea) error) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	currentContent, err := i.readIdeas()
+	if err != nil {
+		return err
+	}
+
+	newContent, err := removeIdea(currentContent, id, check)
+	if err != nil {
+		return err
+	}
+
+	return i.writeIdeas(newContent)
+}
+
+// readIdeas returns every stored idea, or none if the file doesn't exist yet.
+func (i *IdeasFileClient) readIdeas() ([]*ideas.Idea, error) {
+	data, err := os.ReadFile(i.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ideas file %s: %v", i.path, err)
+	}
+
+	var currentContent []*ideas.Idea
+	err = json.Unmarshal(data, &currentContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse into idea array: %v", err)
+	}
+	return currentContent, nil
+}
+
+// writeIdeas overwrites the file with allIdeas.
+func (i *IdeasFileClient) writeIdeas(allIdeas []*ideas.Idea) error {
+	jsonNewContent, err := json.Marshal(allIdeas)
+	if err != nil {
+		return fmt.Errorf("failed to get json idea array: %v", err)
+	}
+
+	return i.writeData(jsonNewContent)
+}
+
+// writeData overwrites the file with data, keeping its current content as a
+// revision first. Nothing is written if data is the current content.
+func (i *IdeasFileClient) writeData(data []byte) error {
+	info, err := os.Stat(i.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to stat ideas file %s: %v", i.path, err)
+	}
+
+	if info != nil {
+		current, err := os.ReadFile(i.path)
+		if err != nil {
+			return fmt.Errorf("failed to read ideas file %s: %v", i.path, err)
+		}
+		if bytes.Equal(current, data) {
+			return nil
+		}
+
+		if err := i.saveRevision(info, current); err != nil {
+			return fmt.Errorf("failed to save revision of ideas file %s: %v", i.path, err)
+		}
+	}
+
+	if _, err := storage.WriteFileGeneration(i.path, data); err != nil {
+		return fmt.Errorf("failed to write ideas file %s: %v", i.path, err)
+	}
+	return nil
+}
+
+// saveRevision copies current, the content of the file described by info,
+// to the revisions directory, and deletes the revisions past maxRevisions.
+// Nothing is saved if revisions are disabled.
+func (i *IdeasFileClient) saveRevision(info fs.FileInfo, current []byte) error {
+	if i.revisionsPath == "" {
+		return nil
+	}
+
+	if err := storage.WriteFileAtomically(i.revisionPath(fileGeneration(info)), current); err != nil {
+		return err
+	}
+
+	// Failing to prune only wastes disk space, so the write goes on.
+	if err := i.pruneRevisions(); err != nil {
+		slog.Error("failed to prune revisions of ideas file", "path", i.path, "err", err)
+	}
+	return nil
+}
+
+// pruneRevisions deletes the oldest past versions of the file, so only
+// maxRevisions of them are kept.
+func (i *IdeasFileClient) pruneRevisions() error {
+	entries, err := os.ReadDir(i.revisionsPath)
+	if err != nil {
+		return err
+	}
+
+	var generations []int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if revision, ok := pastFileRevision(info); ok {
+			generations = append(generations, revision.Generation)
+		}
+	}
+
+	slices.Sort(generations)
+	var errs []error
+	for _, generation := range generations[:max(len(generations)-i.maxRevisions, 0)] {
+		errs = append(errs, os.Remove(i.revisionPath(generation)))
+	}
+	return errors.Join(errs...)
+}
+
+// ListRevisions lists the current file and its past versions.
+func (i *IdeasFileClient) ListRevisions(_ context.Context) ([]*ideas.Revision, error) {
+	var revisions []*ideas.Revision
+
+	current, err := os.Stat(i.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		revisions = append(revisions, fileRevision(current, true))
+	}
+
+	entries, err := os.ReadDir(i.revisionsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return revisions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		revision, ok := pastFileRevision(info)
+		if !ok || (current != nil && revision.Generation == fileGeneration(current)) {
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+
+	slices.SortFunc(revisions, func(a, b *ideas.Revision) int {
+		return cmp.Compare(b.Generation, a.Generation)
+	})
+	return revisions, nil
+}
+
+// GetRevision reads the current file or one of its past versions.
+func (i *IdeasFileClient) GetRevision(_ context.Context, generation int64) (*ideas.Revision, []byte, error) {
+	path := i.revisionPath(generation)
+	isCurrent := false
+
+	current, err := os.Stat(i.path)
+	if err == nil && fileGeneration(current) == generation {
+		path = i.path
+		isCurrent = true
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ideas.Revision{
+		Generation: generation,
+		Time:       time.Unix(0, generation),
+		Size:       info.Size(),
+		Current:    isCurrent,
+	}, data, nil
+}
+
+// RestoreRevision overwrites the file with one of its versions. The current
+// content is kept as a revision, like any other write.
+func (i *IdeasFileClient) RestoreRevision(ctx context.Context, generation int64) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	_, data, err := i.GetRevision(ctx, generation)
+	if err != nil {
+		return err
+	}
+
+	return i.writeData(data)
+}
+
+func (i *IdeasFileClient) revisionPath(generation int64) string {
+	return filepath.Join(i.revisionsPath, strconv.FormatInt(generation, 10)+".json")
+}
+
+// fileGeneration is the generation of a version of the file: the UnixNano of
+// its modification time, so newer versions have higher generations.
+func fileGeneration(info fs.FileInfo) int64 {
+	return info.ModTime().UnixNano()
+}
+
+func fileRevision(info fs.FileInfo, isCurrent bool) *ideas.Revision {
+	return &ideas.Revision{
+		Generation: fileGeneration(info),
+		Time:       info.ModTime(),
+		Size:       info.Size(),
+		Current:    isCurrent,
+	}
+}
+
+// pastFileRevision converts a file of the revisions directory, whose name is
+// its generation. Other files are ignored.
+func pastFileRevision(info fs.FileInfo) (*ideas.Revision, bool) {
+	name, found := strings.CutSuffix(info.Name(), ".json")
+	if !found || info.IsDir() {
+		return nil, false
+	}
+
+	generation, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	return &ideas.Revision{
+		Generation: generation,
+		Time:       time.Unix(0, generation),
+		Size:       info.Size(),
+	}, true
+}
@@ -0,0 +1,66 @@
+package ideas
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+func TestFileRevisionsRetention(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewIdeasFileClient(config.Ideas{Path: filepath.Join(dir, "ideas.json"), MaxRevisions: 2})
+	ctx := context.Background()
+
+	countRevisions := func() int {
+		t.Helper()
+
+		entries, err := os.ReadDir(filepath.Join(dir, "ideas.json.revisions"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	// The first post creates the file, which has no past version yet.
+	posted := postIdeas(t, repo, "one", "two", "three", "four")
+	if got := countRevisions(); got != 2 {
+		t.Errorf("%d revisions after 4 posts, want 2", got)
+	}
+
+	before, err := repo.ListRevisions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates that change nothing create no revision.
+	for range 2 {
+		_, err := repo.UpdateIdea(ctx, posted[0].ID, func(*ideas.Idea) error { return nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := repo.ListRevisions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || !revisions[0].Current {
+		t.Fatalf("ListRevisions() = %+v, want the current file and 2 past versions", revisions)
+	}
+	if revisions[0].Generation != before[0].Generation {
+		t.Error("updates that change nothing wrote the file")
+	}
+
+	// The newest past versions are kept: the one before the last post has 3 ideas.
+	_, data, err := repo.GetRevision(ctx, revisions[1].Generation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parseIdeas(&ideas.GetIdeasRequest{Limit: 10}, data); len(got) != 3 {
+		t.Errorf("newest past version has %d ideas, want 3", len(got))
+	}
+}
@@ -1,21 +1,28 @@
 package ideas
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"os"
+	"log/slog"
+	"net/http"
+	"slices"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/metrics"
+	"github.com/jaehnri/website-backend/internal/tracing"
 	"github.com/jaehnri/website-backend/pkg/ideas"
-)
-
-const (
-	BucketEnv = "GCS_BUCKET_ENV"
-	ObjectEnv = "GCS_OBJECT_ENV"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 )
 
 type IdeasGCSClient struct {
@@ -23,108 +30,305 @@ type IdeasGCSClient struct {
 	gcsClient *storage.Client
 }
 
-func NewIdeasGCSClient() *IdeasGCSClient {
-	bucketName, exists := os.LookupEnv(BucketEnv)
-	if !exists {
-		log.Panic("couldn't retrieve GCS bucket")
-	}
-
-	objectName, exists := os.LookupEnv(ObjectEnv)
-	if !exists {
-		log.Panic("couldn't retrieve GCS object")
-	}
-
+func NewIdeasGCSClient(cfg config.Ideas) (*IdeasGCSClient, error) {
 	client, err := storage.NewClient(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to create GCS client: %v", err)
+		return nil, fmt.Errorf("failed to create GCS client: %v", err)
 	}
 
-	obj := client.Bucket(bucketName).Object(objectName)
+	obj := client.Bucket(cfg.Bucket).Object(cfg.Object)
 	return &IdeasGCSClient{
 		gcsClient: client,
 		object:    obj,
-	}
+	}, nil
 }
 
 // GetIdeas fetches all the ideas from a single GCS object.
-func (i *IdeasGCSClient) GetIdeas(req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error) {
-	rc, err := i.object.NewReader(context.Background())
-	if err != nil {
-		log.Printf("failed to create object reader for %s/%s: %v", i.object.BucketName(), i.object.ObjectName(), err)
+func (i *IdeasGCSClient) GetIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error) {
+	data, _, err := i.readObject(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		// No idea was posted yet.
+		data = []byte("[]")
+	} else if err != nil {
+		slog.ErrorContext(ctx, "failed to read ideas object", "bucket", i.object.BucketName(), "object", i.object.ObjectName(), "err", err)
+		return nil, err
 	}
-	defer rc.Close()
 
-	data, err := io.ReadAll(rc)
+	return &ideas.GetIdeasResponse{
+		Ideas: parseIdeas(req, data),
+	}, nil
+}
+
+// CheckStorage reads the object metadata. A missing object is fine, as it's
+// created by the first post.
+func (i *IdeasGCSClient) CheckStorage(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "gcs.attrs", i.objectAttribute())
+	start := time.Now()
+	_, err := i.object.Attrs(ctx)
+	metrics.ObserveGCS("attrs", start, err)
+	tracing.End(span, err)
+
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (i *IdeasGCSClient) PostIdea(ctx context.Context, req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error) {
+	// TODO: Encode the Idea into a proto? I feel really dirty using JSONs.
+	idea := idea(req)
+
+	// Note that by appending fresh ideas to the start, it's easy to use limits and offsets later
+	// without sorting.
+	err := i.modifyIdeas(ctx, func(currentContent []*ideas.Idea) ([]*ideas.Idea, error) {
+		return prependIdea(currentContent, idea)
+	})
 	if err != nil {
-		log.Printf("failed to read object contents: %v", err)
+		return nil, err
 	}
 
-	return &ideas.GetIdeasResponse{
-		Ideas: parseIdeas(req, data),
+	return &ideas.PostIdeaResponse{
+		Idea: idea,
 	}, nil
 }
 
-func (i *IdeasGCSClient) PostIdea(req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error) {
-	// 1. Read the existing content of the object
-	rc, err := i.object.NewReader(context.Background())
+// UpdateIdea applies update to the stored idea with id.
+func (i *IdeasGCSClient) UpdateIdea(ctx context.Context, id string, update func(*ideas.Idea) error) (*ideas.Idea, error) {
+	var updated *ideas.Idea
+	err := i.modifyIdeas(ctx, func(currentContent []*ideas.Idea) ([]*ideas.Idea, error) {
+		var err error
+		updated, err = replaceIdea(currentContent, id, update)
+		return currentContent, err
+	})
 	if err != nil {
-		// Handle the case where the object might not exist yet.
-		// If it's a new file, we'll just start with the new idea.
-		if err == storage.ErrObjectNotExist {
-			log.Printf("Object gs://%s/%s does not exist. Creating...", i.object.BucketName(), i.object.ObjectName())
-			// No existing content, so currentContent will be empty.
-		} else {
-			return nil, fmt.Errorf("failed to create object reader for %s/%s: %v", i.object.BucketName(), i.object.ObjectName(), err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteIdea removes the stored idea with id for good.
+func (i *IdeasGCSClient) DeleteIdea(ctx context.Context, id string, check func(*ideas.Idea) error) error {
+	return i.modifyIdeas(ctx, func(currentContent []*ideas.Idea) ([]*ideas.Idea, error) {
+		return removeIdea(currentContent, id, check)
+	})
+}
+
+// maxWriteAttempts is how many times modifyIdeas reads and writes the object
+// before giving up, when other writes keep getting in between.
+const maxWriteAttempts = 5
+
+// modifyIdeas reads every idea, applies modify to them and writes the result,
+// only if the object is still the generation that was read. Otherwise, a
+// concurrent write, e.g. of another replica, got in between, and modify is
+// applied again to the newer ideas, so neither write is lost.
+func (i *IdeasGCSClient) modifyIdeas(ctx context.Context, modify func([]*ideas.Idea) ([]*ideas.Idea, error)) error {
+	for attempt := 1; ; attempt++ {
+		currentContent, generation, err := i.readIdeas(ctx)
+		if err != nil {
+			return err
 		}
+
+		newContent, err := modify(currentContent)
+		if err != nil {
+			return err
+		}
+
+		err = i.writeIdeas(ctx, newContent, generation)
+		if !isPreconditionFailed(err) || attempt == maxWriteAttempts {
+			return err
+		}
+		slog.InfoContext(ctx, "ideas object changed while modifying it, retrying", "attempt", attempt)
 	}
-	defer func() {
-		if rc != nil { // Ensure rc is not nil before closing
-			rc.Close()
+}
+
+// isPreconditionFailed tells whether err is a 412 of GCS, returned by writes
+// with conditions that don't hold.
+func isPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
+}
+
+// ListRevisions lists the generations of the object. Past generations are only
+// kept if the bucket has object versioning enabled.
+func (i *IdeasGCSClient) ListRevisions(ctx context.Context) ([]*ideas.Revision, error) {
+	ctx, span := tracing.Start(ctx, "gcs.list", i.objectAttribute())
+	start := time.Now()
+	revisions, err := func() ([]*ideas.Revision, error) {
+		var revisions []*ideas.Revision
+
+		it := i.gcsClient.Bucket(i.object.BucketName()).Objects(ctx, &storage.Query{
+			Prefix:   i.object.ObjectName(),
+			Versions: true,
+		})
+		for {
+			attrs, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				return revisions, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			// Other objects may share the prefix.
+			if attrs.Name == i.object.ObjectName() {
+				revisions = append(revisions, revision(attrs))
+			}
 		}
 	}()
+	metrics.ObserveGCS("list", start, err)
+	tracing.End(span, err)
 
-	var currentContent []*ideas.Idea
-	if rc != nil { // Only read if the object existed
-		dataBytes, err := io.ReadAll(rc)
+	slices.SortFunc(revisions, func(a, b *ideas.Revision) int {
+		return cmp.Compare(b.Generation, a.Generation)
+	})
+	return revisions, err
+}
+
+// GetRevision reads a generation of the object.
+func (i *IdeasGCSClient) GetRevision(ctx context.Context, generation int64) (*ideas.Revision, []byte, error) {
+	object := i.object.Generation(generation)
+
+	ctx, span := tracing.Start(ctx, "gcs.read", i.objectAttribute(), attribute.Int64("gcs.generation", generation))
+	start := time.Now()
+	attrs, data, err := func() (*storage.ObjectAttrs, []byte, error) {
+		rc, err := object.NewReader(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read object contents: %v", err)
+			return nil, nil, err
 		}
+		defer rc.Close()
 
-		err = json.Unmarshal(dataBytes, &currentContent)
+		data, err := io.ReadAll(rc)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse into idea array: %v", err)
+			return nil, nil, err
 		}
+
+		attrs, err := object.Attrs(ctx)
+		return attrs, data, err
+	}()
+	metrics.ObserveGCS("read", start, err)
+	tracing.End(span, err)
+
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, nil, err
 	}
+	return revision(attrs), data, nil
+}
 
-	// 2. Modify the content in memory by appending the new idea
-	// TODO: Encode the Idea into a proto? I feel really dirty using JSONs.
-	idea := idea(req)
+// RestoreRevision copies a generation of the object over the live one, which
+// creates a new generation.
+func (i *IdeasGCSClient) RestoreRevision(ctx context.Context, generation int64) error {
+	ctx, span := tracing.Start(ctx, "gcs.copy", i.objectAttribute(), attribute.Int64("gcs.generation", generation))
+	start := time.Now()
+	_, err := i.object.CopierFrom(i.object.Generation(generation)).Run(ctx)
+	metrics.ObserveGCS("copy", start, err)
+	tracing.End(span, err)
 
-	// Note that by appending fresh ideas to the start, it's easy to use limits and offsets later
-	// without sorting.
-	newContent := append([]*ideas.Idea{idea}, currentContent...)
-	jsonNewContent, err := json.Marshal(newContent)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrRevisionNotFound
+	}
+	return err
+}
+
+// revision converts the attributes of a generation. Past generations have a
+// deletion time, the live one doesn't.
+func revision(attrs *storage.ObjectAttrs) *ideas.Revision {
+	return &ideas.Revision{
+		Generation: attrs.Generation,
+		Time:       attrs.Created,
+		Size:       attrs.Size,
+		Current:    attrs.Deleted.IsZero(),
+	}
+}
+
+// readIdeas returns every stored idea and the generation of the object, or
+// none and generation 0 if the object doesn't exist yet.
+func (i *IdeasGCSClient) readIdeas(ctx context.Context) ([]*ideas.Idea, int64, error) {
+	dataBytes, generation, err := i.readObject(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		// If it's a new file, we'll just start with the new idea.
+		slog.InfoContext(ctx, "ideas object does not exist, creating it", "bucket", i.object.BucketName(), "object", i.object.ObjectName())
+		return nil, 0, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get json idea array: %v", err)
+		return nil, 0, fmt.Errorf("failed to read object %s/%s: %v", i.object.BucketName(), i.object.ObjectName(), err)
 	}
 
-	// 3. Overwrite the original object with the new content
-	wc := i.object.NewWriter(context.Background())
-	wc.ContentType = "application/json"
+	var currentContent []*ideas.Idea
+	err = json.Unmarshal(dataBytes, &currentContent)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse into idea array: %v", err)
+	}
+	return currentContent, generation, nil
+}
 
-	if _, err := fmt.Fprint(wc, string(jsonNewContent)); err != nil {
-		wc.Close()
-		return nil, fmt.Errorf("failed to write new content to object: %v", err)
+// writeIdeas overwrites the object with allIdeas, if it's still at generation,
+// or creates it if generation is 0.
+func (i *IdeasGCSClient) writeIdeas(ctx context.Context, allIdeas []*ideas.Idea, generation int64) error {
+	jsonNewContent, err := json.Marshal(allIdeas)
+	if err != nil {
+		return fmt.Errorf("failed to get json idea array: %v", err)
 	}
 
-	if err := wc.Close(); err != nil {
-		log.Printf("failed to close object writer: %v", err)
-		return nil, err
+	if err := i.writeObject(ctx, jsonNewContent, generation); err != nil {
+		slog.ErrorContext(ctx, "failed to write ideas object", "bucket", i.object.BucketName(), "object", i.object.ObjectName(), "err", err)
+		return err
 	}
+	return nil
+}
 
-	return &ideas.PostIdeaResponse{
-		Idea: idea,
-	}, nil
+// readObject returns the contents and the generation of the ideas object,
+// recording the read in metrics.
+func (i *IdeasGCSClient) readObject(ctx context.Context) ([]byte, int64, error) {
+	ctx, span := tracing.Start(ctx, "gcs.read", i.objectAttribute())
+	start := time.Now()
+	data, generation, err := func() ([]byte, int64, error) {
+		rc, err := i.object.NewReader(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		return data, rc.Attrs.Generation, err
+	}()
+	metrics.ObserveGCS("read", start, err)
+	tracing.End(span, err)
+
+	return data, generation, err
+}
+
+// writeObject overwrites the ideas object if it's still at generation, or
+// creates it if generation is 0, recording the write in metrics. Otherwise,
+// GCS answers 412 Precondition Failed.
+func (i *IdeasGCSClient) writeObject(ctx context.Context, data []byte, generation int64) error {
+	conditions := storage.Conditions{GenerationMatch: generation}
+	if generation == 0 {
+		conditions = storage.Conditions{DoesNotExist: true}
+	}
+
+	ctx, span := tracing.Start(ctx, "gcs.write", i.objectAttribute())
+	start := time.Now()
+	err := func() error {
+		wc := i.object.If(conditions).NewWriter(ctx)
+		wc.ContentType = "application/json"
+
+		if _, err := wc.Write(data); err != nil {
+			wc.Close()
+			return err
+		}
+		return wc.Close()
+	}()
+	metrics.ObserveGCS("write", start, err)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (i *IdeasGCSClient) objectAttribute() attribute.KeyValue {
+	return attribute.String("gcs.object", i.object.ObjectName())
 }
 
 // Since every idea is a single line, parseIdeas receives all ideas in a single
@@ -134,17 +338,124 @@ func parseIdeas(req *ideas.GetIdeasRequest, fileContent []byte) []*ideas.Idea {
 
 	err := json.Unmarshal(fileContent, &ideas)
 	if err != nil {
-		log.Print("failed to unmarshal idea from gcs: ", err)
+		slog.Error("failed to unmarshal ideas", "err", err)
 	}
 
-	ideas = ideas[req.Offset:]
-	limit := min(req.Limit, len(ideas))
-	return ideas[:limit]
+	return prepareIdeas(req, ideas)
 }
 
+// prepareIdeas fills in what older ideas lack, sorts them and returns the
+// page of req.
+func prepareIdeas(req *ideas.GetIdeasRequest, allIdeas []*ideas.Idea) []*ideas.Idea {
+	if req.Offset >= len(allIdeas) {
+		return nil
+	}
+
+	fillIDs(allIdeas)
+	fillTags(allIdeas)
+	sortNewestFirst(allIdeas)
+	allIdeas = allIdeas[req.Offset:]
+	limit := min(req.Limit, len(allIdeas))
+	return allIdeas[:limit]
+}
+
+// idea creates the idea of req, which must have been validated with
+// validatePostIdeaRequest.
 func idea(req *ideas.PostIdeaRequest) *ideas.Idea {
-	return &ideas.Idea{
-		Idea: req.Idea,
-		Time: time.Now(),
+	received := time.Now()
+	idea := &ideas.Idea{
+		ID:     newIdeaID(received),
+		Idea:   req.Idea,
+		Time:   received,
+		Tags:   ideaTags(req.Tags, req.Idea),
+		Status: req.Status,
+	}
+	if !req.PublishAt.IsZero() {
+		idea.Time = req.PublishAt
+	}
+	return idea
+}
+
+// prependIdea returns allIdeas with idea first, or ErrIdeaExists if one of
+// them has its ID already.
+func prependIdea(allIdeas []*ideas.Idea, idea *ideas.Idea) ([]*ideas.Idea, error) {
+	if indexIdea(allIdeas, idea.ID) >= 0 {
+		return nil, ErrIdeaExists
+	}
+	return append([]*ideas.Idea{idea}, allIdeas...), nil
+}
+
+// replaceIdea applies update to a copy of the idea with id in allIdeas, and
+// replaces the idea with it. It returns the updated idea.
+func replaceIdea(allIdeas []*ideas.Idea, id string, update func(*ideas.Idea) error) (*ideas.Idea, error) {
+	i := indexIdea(allIdeas, id)
+	if i < 0 {
+		return nil, ErrIdeaNotFound
+	}
+
+	updated := *allIdeas[i]
+	if err := update(&updated); err != nil {
+		return nil, err
+	}
+
+	allIdeas[i] = &updated
+	return &updated, nil
+}
+
+// removeIdea returns allIdeas without the idea with id, unless check returns
+// an error for it.
+func removeIdea(allIdeas []*ideas.Idea, id string, check func(*ideas.Idea) error) ([]*ideas.Idea, error) {
+	i := indexIdea(allIdeas, id)
+	if i < 0 {
+		return nil, ErrIdeaNotFound
+	}
+
+	if err := check(allIdeas[i]); err != nil {
+		return nil, err
+	}
+	return slices.Delete(allIdeas, i, i+1), nil
+}
+
+// indexIdea returns the position of the idea with id in allIdeas, or -1.
+func indexIdea(allIdeas []*ideas.Idea, id string) int {
+	fillIDs(allIdeas)
+
+	return slices.IndexFunc(allIdeas, func(candidate *ideas.Idea) bool {
+		return candidate.ID == id
+	})
+}
+
+// sortNewestFirst sorts ideas by time. Stored ideas are mostly sorted already,
+// but scheduled ideas are stored when posted, not when published.
+func sortNewestFirst(allIdeas []*ideas.Idea) {
+	slices.SortStableFunc(allIdeas, func(a, b *ideas.Idea) int {
+		return b.Time.Compare(a.Time)
+	})
+}
+
+// newIdeaID returns the ID of an idea received by the server at received.
+// Random bytes keep IDs unique, even when the same idea is posted twice at
+// once, e.g. when a client retries.
+func newIdeaID(received time.Time) string {
+	random := make([]byte, 8)
+	rand.Read(random)
+
+	sum := sha256.Sum256([]byte(received.UTC().Format(time.RFC3339Nano) + "\n" + hex.EncodeToString(random)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ideaID derives the ID of an idea stored before IDs existed from its time
+// and text.
+func ideaID(idea *ideas.Idea) string {
+	sum := sha256.Sum256([]byte(idea.Time.UTC().Format(time.RFC3339Nano) + "\n" + idea.Idea))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fillIDs sets the ID of ideas stored before IDs existed.
+func fillIDs(allIdeas []*ideas.Idea) {
+	for _, idea := range allIdeas {
+		if idea.ID == "" {
+			idea.ID = ideaID(idea)
+		}
 	}
 }
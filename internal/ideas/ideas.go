@@ -1,10 +1,24 @@
 package ideas
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jaehnri/website-backend/internal/cache"
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/httpcache"
+	"github.com/jaehnri/website-backend/internal/search"
 	"github.com/jaehnri/website-backend/pkg/ideas"
 )
 
@@ -13,53 +27,453 @@ const (
 	DefaultLimit  = 50
 )
 
+var ErrIdeaNotFound = errors.New("idea not found")
+
+// ErrIdeaConflict is returned by updates and deletes of ideas that changed
+// since they were read, e.g. when another replica published a scheduled idea
+// first.
+var ErrIdeaConflict = errors.New("idea changed concurrently")
+
+// ErrIdeaExists is returned by posts of ideas whose ID is already stored.
+var ErrIdeaExists = errors.New("idea already exists")
+
 type IdeasRepository interface {
-	GetIdeas(req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error)
-	PostIdea(req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error)
+	GetIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error)
+
+	// PostIdea stores a new idea, or returns ErrIdeaExists if its ID is taken.
+	PostIdea(ctx context.Context, req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error)
+
+	// UpdateIdea applies update to a copy of the stored idea with id and
+	// stores it, atomically: the idea can't change in between, even on other
+	// replicas. It returns the updated idea, ErrIdeaNotFound, or the error of
+	// update, e.g. ErrIdeaConflict if the stored idea can't be updated anymore.
+	UpdateIdea(ctx context.Context, id string, update func(idea *ideas.Idea) error) (*ideas.Idea, error)
+
+	// DeleteIdea removes the stored idea with id for good, unless check
+	// returns an error for it, atomically like UpdateIdea. It returns
+	// ErrIdeaNotFound if there's none. Ideas are deleted by setting DeletedAt
+	// first, and only purged from the trash later.
+	DeleteIdea(ctx context.Context, id string, check func(idea *ideas.Idea) error) error
+}
+
+// StorageChecker is an IdeasRepository that can tell whether its storage is reachable.
+type StorageChecker interface {
+	CheckStorage(ctx context.Context) error
+}
+
+// IdeasQuerier is an IdeasRepository that filters and pages published ideas
+// itself, e.g. with database indexes, instead of the in-memory snapshots.
+type IdeasQuerier interface {
+	// QueryIdeas returns the page of published ideas requested by req,
+	// newest first, with the cursor of the next page and the total.
+	QueryIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error)
+}
+
+// IdeasSearcher is an IdeasRepository that searches published ideas itself,
+// instead of the in-memory index of snapshots.
+type IdeasSearcher interface {
+	// SearchIdeas returns a page of the published ideas matching req.Query
+	// and the filters of req, most relevant first, without highlights.
+	SearchIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.SearchIdeasResponse, error)
 }
 
 type IdeasClient struct {
 	ideasRepo IdeasRepository
+
+	// cache holds all ideas under allIdeasKey, so reads don't hit the
+	// repository every time. Posting an idea adds it to the cached snapshot.
+	cache *cache.TTLCache[*ideasSnapshot]
+
+	// snapshotLock serializes loading snapshots and adding posted ideas to
+	// them, so a slow load can't cache a snapshot without a newer idea.
+	snapshotLock sync.Mutex
+
+	// cacheControl is the Cache-Control header of GET /ideas and feeds.
+	cacheControl string
+
+	// feedMetadata describes the feeds of ideas.
+	feedMetadata config.IdeasFeed
+
+	// schedulerInterval is how often scheduled ideas are published.
+	schedulerInterval time.Duration
+
+	// Deleted ideas are purged every purgeInterval, once they've been in the
+	// trash for trashRetention.
+	trashRetention time.Duration
+	purgeInterval  time.Duration
+
+	// compactionInterval is how often repositories that need it are compacted.
+	compactionInterval time.Duration
+
+	// observers are notified of events, e.g. when ideas are published.
+	observers []Observer
 }
 
-func NewIdeasClient() *IdeasClient {
-	return &IdeasClient{
-		ideasRepo: NewIdeasGCSClient(),
+// ideasSnapshot is the whole list of ideas at some point, newest first.
+type ideasSnapshot struct {
+	// all holds every idea, including unpublished ones.
+	all []*ideas.Idea
+
+	// ideas holds the published ideas, the only public ones.
+	ideas []*ideas.Idea
+
+	// drafts holds draft and scheduled ideas.
+	drafts []*ideas.Idea
+
+	// trash holds deleted ideas.
+	trash []*ideas.Idea
+
+	// lastModified is when the published ideas last changed, according to
+	// the stored ideas, so it's the same on every replica and after restarts.
+	lastModified time.Time
+
+	// byID and index are used to search published ideas. The index is shared
+	// by the snapshots that follow when ideas are posted, so it may know about
+	// newer ideas than byID.
+	byID  map[string]*ideas.Idea
+	index *search.Index
+
+	// byTag holds the published ideas with each tag, newest first.
+	byTag map[string][]*ideas.Idea
+}
+
+const allIdeasKey = "all"
+
+func NewIdeasClient(cfg config.Ideas) (*IdeasClient, error) {
+	ideasRepo, err := newIdeasRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &IdeasClient{
+		ideasRepo:          ideasRepo,
+		cache:              cache.NewTTLCache[*ideasSnapshot]("ideas", cfg.CacheTTL, 1),
+		cacheControl:       cfg.CacheControl,
+		feedMetadata:       cfg.Feed,
+		schedulerInterval:  cfg.SchedulerInterval,
+		trashRetention:     cfg.TrashRetention,
+		purgeInterval:      cfg.PurgeInterval,
+		compactionInterval: cfg.CompactionInterval,
 	}
+	client.Subscribe(logEvent)
+	return client, nil
 }
 
-func (s *IdeasClient) HandleIdeas(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		s.HandleGetIdeas(w, r)
-	case http.MethodPost:
-		s.HandlePostIdeas(w, r)
-	default:
-		// Respond with 405 Method Not Allowed for other methods
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+func newIdeasRepository(cfg config.Ideas) (IdeasRepository, error) {
+	if cfg.Backend == config.IdeasBackendSQLite {
+		return NewIdeasSQLiteClient(cfg)
+	}
+	if cfg.Layout == config.IdeasLayoutObjects {
+		return NewIdeasObjectsClient(cfg)
+	}
+	if cfg.Backend == config.IdeasBackendFilesystem {
+		return NewIdeasFileClient(cfg), nil
 	}
+	return NewIdeasGCSClient(cfg)
+}
 
+// CheckStorage tells whether ideas can be read. Repositories that can't tell
+// are assumed to be reachable.
+func (s *IdeasClient) CheckStorage(ctx context.Context) error {
+	if checker, ok := s.ideasRepo.(StorageChecker); ok {
+		return checker.CheckStorage(ctx)
+	}
+	return nil
 }
 
+// HandleGetIdeas receives an HTTP request and returns a page of ideas in
+// GetIdeasResponse format. Responses carry an ETag and the time of the newest
+// idea as Last-Modified, so clients can revalidate them.
 func (s *IdeasClient) HandleGetIdeas(w http.ResponseWriter, r *http.Request) {
-	ideas, err := s.ideasRepo.GetIdeas(parseGetIdeasRequest(r))
+	req, err := parseGetIdeasRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Query != "" {
+		s.serveSearch(w, r, req)
+		return
+	}
+	if querier, ok := s.ideasRepo.(IdeasQuerier); ok {
+		s.serveQuery(w, r, querier, req)
+		return
+	}
+
+	snapshot, err := s.getIdeas(r.Context())
 	if err != nil {
 		http.Error(w, "failed to fetch my ideas", http.StatusInternalServerError)
 		return
 	}
 
-	// TODO: Think about this later!
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(ideas)
+	filtered := filterIdeas(snapshot, req)
+	pageIdeas, nextCursor, err := paginate(filtered, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Render == ideas.RenderHTML {
+		pageIdeas, err = renderIdeas(pageIdeas)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to render ideas", "err", err)
+			http.Error(w, "failed to render ideas", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	body, err := json.Marshal(&ideas.GetIdeasResponse{
+		Ideas:      pageIdeas,
+		NextCursor: nextCursor,
+		Total:      len(filtered),
+	})
 	if err != nil {
 		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
 		return
 	}
+
+	setLinkHeader(w, r, nextCursor)
+	httpcache.ServeJSONModified(w, r, body, s.cacheControl, snapshot.lastModified)
 }
 
-func parseGetIdeasRequest(r *http.Request) *ideas.GetIdeasRequest {
+// serveQuery returns the page of ideas of req, filtered and paged by querier.
+// Responses only carry an ETag, as finding when ideas last changed would take
+// loading all of them.
+func (s *IdeasClient) serveQuery(w http.ResponseWriter, r *http.Request, querier IdeasQuerier, req *ideas.GetIdeasRequest) {
+	response, err := querier.QueryIdeas(r.Context(), req)
+	if errors.Is(err, errInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to query ideas", "err", err)
+		http.Error(w, "failed to fetch my ideas", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Render == ideas.RenderHTML {
+		response.Ideas, err = renderIdeas(response.Ideas)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to render ideas", "err", err)
+			http.Error(w, "failed to render ideas", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+
+	setLinkHeader(w, r, response.NextCursor)
+	httpcache.ServeJSON(w, r, body, s.cacheControl)
+}
+
+// WarmUp loads all ideas and indexes them for search, so the first requests
+// don't have to.
+func (s *IdeasClient) WarmUp(ctx context.Context) {
+	start := time.Now()
+
+	snapshot, err := s.getIdeas(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to index ideas", "err", err)
+		return
+	}
+
+	slog.InfoContext(ctx, "indexed ideas", "count", len(snapshot.ideas), "duration", time.Since(start))
+}
+
+// getIdeas returns all ideas, from the cache if possible. Ideas loaded from
+// the repository are indexed from scratch.
+func (s *IdeasClient) getIdeas(ctx context.Context) (*ideasSnapshot, error) {
+	if snapshot, exists := s.cache.Get(allIdeasKey); exists {
+		return snapshot, nil
+	}
+
+	s.snapshotLock.Lock()
+	defer s.snapshotLock.Unlock()
+
+	// Another request may have loaded ideas while this one waited.
+	if snapshot, exists := s.cache.Get(allIdeasKey); exists {
+		return snapshot, nil
+	}
+
+	response, err := s.ideasRepo.GetIdeas(ctx, &ideas.GetIdeasRequest{Offset: 0, Limit: math.MaxInt})
+	if err != nil {
+		return nil, err
+	}
+
+	index := search.NewIndex()
+	for _, idea := range response.Ideas {
+		if isPublished(idea) {
+			index.Add(idea.ID, idea.Idea)
+		}
+	}
+
+	return s.cacheSnapshot(response.Ideas, index), nil
+}
+
+// applyIdea adds a new or updated idea to the cached snapshot, if any, and
+// indexes it if it's published.
+func (s *IdeasClient) applyIdea(idea *ideas.Idea) {
+	s.snapshotLock.Lock()
+	defer s.snapshotLock.Unlock()
+
+	snapshot, exists := s.cache.Get(allIdeasKey)
+	if !exists {
+		return
+	}
+
+	if isPublished(idea) {
+		snapshot.index.Add(idea.ID, idea.Idea)
+	} else {
+		snapshot.index.Remove(idea.ID)
+	}
+
+	allIdeas := []*ideas.Idea{idea}
+	for _, other := range snapshot.all {
+		if other.ID != idea.ID {
+			allIdeas = append(allIdeas, other)
+		}
+	}
+	sortNewestFirst(allIdeas)
+
+	s.cacheSnapshot(allIdeas, snapshot.index)
+}
+
+// forgetIdea removes a purged idea from the cached snapshot, if any.
+func (s *IdeasClient) forgetIdea(id string) {
+	s.snapshotLock.Lock()
+	defer s.snapshotLock.Unlock()
+
+	snapshot, exists := s.cache.Get(allIdeasKey)
+	if !exists {
+		return
+	}
+
+	snapshot.index.Remove(id)
+	allIdeas := slices.DeleteFunc(slices.Clone(snapshot.all), func(idea *ideas.Idea) bool {
+		return idea.ID == id
+	})
+	s.cacheSnapshot(allIdeas, snapshot.index)
+}
+
+// cacheSnapshot caches and returns the snapshot of allIdeas.
+// s.snapshotLock must be held.
+func (s *IdeasClient) cacheSnapshot(allIdeas []*ideas.Idea, index *search.Index) *ideasSnapshot {
+	snapshot := newIdeasSnapshot(allIdeas, index)
+	s.cache.Set(allIdeasKey, snapshot)
+	return snapshot
+}
+
+// lastModified returns when the published ideas of allIdeas last changed:
+// when the newest one was posted or published, or when the last published
+// one was deleted.
+func lastModified(allIdeas []*ideas.Idea) time.Time {
+	var last time.Time
+	for _, idea := range allIdeas {
+		changed := idea.Time
+		if !idea.DeletedAt.IsZero() {
+			changed = idea.DeletedAt
+		}
+
+		// Drafts and scheduled ideas were never public, even if deleted.
+		if idea.Status != "" && idea.Status != ideas.StatusPublished {
+			continue
+		}
+		if changed.After(last) {
+			last = changed
+		}
+	}
+	return last
+}
+
+// newIdeasSnapshot returns the snapshot of allIdeas, newest first. Only the
+// published ones are indexed.
+func newIdeasSnapshot(allIdeas []*ideas.Idea, index *search.Index) *ideasSnapshot {
+	snapshot := &ideasSnapshot{
+		all:    allIdeas,
+		ideas:  []*ideas.Idea{},
+		drafts: []*ideas.Idea{},
+		trash:  []*ideas.Idea{},
+		byID:   make(map[string]*ideas.Idea, len(allIdeas)),
+		index:  index,
+		byTag:  make(map[string][]*ideas.Idea),
+
+		lastModified: lastModified(allIdeas),
+	}
+	for _, idea := range allIdeas {
+		if !idea.DeletedAt.IsZero() {
+			snapshot.trash = append(snapshot.trash, idea)
+			continue
+		}
+		if !isPublished(idea) {
+			snapshot.drafts = append(snapshot.drafts, idea)
+			continue
+		}
+
+		snapshot.ideas = append(snapshot.ideas, idea)
+		snapshot.byID[idea.ID] = idea
+		for _, tag := range idea.Tags {
+			snapshot.byTag[tag] = append(snapshot.byTag[tag], idea)
+		}
+	}
+	return snapshot
+}
+
+// isPublished tells whether idea is public. Ideas stored before statuses
+// existed have none, and are published. Deleted ideas aren't public.
+func isPublished(idea *ideas.Idea) bool {
+	return idea.DeletedAt.IsZero() && (idea.Status == "" || idea.Status == ideas.StatusPublished)
+}
+
+// filterIdeas returns the ideas of snapshot matching the filters of req,
+// newest first.
+func filterIdeas(snapshot *ideasSnapshot, req *ideas.GetIdeasRequest) []*ideas.Idea {
+	allIdeas := snapshot.ideas
+	if req.Tag != "" {
+		allIdeas = snapshot.byTag[req.Tag]
+	}
+	if req.Since.IsZero() && req.Until.IsZero() {
+		return allIdeas
+	}
+
+	filtered := []*ideas.Idea{}
+	for _, idea := range allIdeas {
+		if matchesFilters(idea, req) {
+			filtered = append(filtered, idea)
+		}
+	}
+	return filtered
+}
+
+// matchesFilters tells whether idea has the tag of req and was posted in its
+// time range, if they're set.
+func matchesFilters(idea *ideas.Idea, req *ideas.GetIdeasRequest) bool {
+	if req.Tag != "" && !slices.Contains(idea.Tags, req.Tag) {
+		return false
+	}
+	if !req.Since.IsZero() && idea.Time.Before(req.Since) {
+		return false
+	}
+	if !req.Until.IsZero() && !idea.Time.Before(req.Until) {
+		return false
+	}
+	return true
+}
+
+// page returns the ideas requested by req, or none if the offset is past the end.
+func page(allIdeas []*ideas.Idea, req *ideas.GetIdeasRequest) []*ideas.Idea {
+	if req.Offset < 0 || req.Offset >= len(allIdeas) || req.Limit <= 0 {
+		return []*ideas.Idea{}
+	}
+
+	allIdeas = allIdeas[req.Offset:]
+	return allIdeas[:min(req.Limit, len(allIdeas))]
+}
+
+func parseGetIdeasRequest(r *http.Request) (*ideas.GetIdeasRequest, error) {
 	query := r.URL.Query()
 
 	offsetStr := query.Get("offset")
@@ -74,10 +488,54 @@ func parseGetIdeasRequest(r *http.Request) *ideas.GetIdeasRequest {
 		limit = DefaultLimit
 	}
 
-	return &ideas.GetIdeasRequest{
+	req := &ideas.GetIdeasRequest{
 		Offset: offset,
 		Limit:  limit,
+		Cursor: query.Get("cursor"),
+		Query:  strings.TrimSpace(query.Get("q")),
+		Render: query.Get("render"),
+	}
+
+	if req.Render != "" && req.Render != ideas.RenderHTML {
+		return nil, errors.New("render must be html")
+	}
+
+	if req.Cursor != "" && (query.Has("offset") || req.Query != "") {
+		return nil, errors.New("cursor can't be used with offset or q")
+	}
+
+	req.Since, err = parseTimestamp(query, "since")
+	if err != nil {
+		return nil, err
+	}
+	req.Until, err = parseTimestamp(query, "until")
+	if err != nil {
+		return nil, err
+	}
+
+	if tag := query.Get("tag"); tag != "" {
+		var ok bool
+		req.Tag, ok = normalizeTag(tag)
+		if !ok {
+			return nil, errors.New("invalid tag")
+		}
 	}
+	return req, nil
+}
+
+// parseTimestamp parses the RFC 3339 timestamp of a query parameter. It's zero
+// if the parameter is missing.
+func parseTimestamp(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
 }
 
 func (s *IdeasClient) HandlePostIdeas(w http.ResponseWriter, r *http.Request) {
@@ -87,14 +545,27 @@ func (s *IdeasClient) HandlePostIdeas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idea, err := s.ideasRepo.PostIdea(req)
+	err = validatePostIdeaRequest(req, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	idea, err := s.ideasRepo.PostIdea(r.Context(), req)
+	if errors.Is(err, ErrIdeaExists) {
+		http.Error(w, "idea already exists", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "failed to post new idea", http.StatusInternalServerError)
 		return
 	}
 
-	// TODO: Think about this later!
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	s.applyIdea(idea.Idea)
+	if isPublished(idea.Idea) {
+		s.emit(r.Context(), &Event{Type: EventPublished, Idea: idea.Idea})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	encoder := json.NewEncoder(w)
 	err = encoder.Encode(idea)
@@ -104,6 +575,32 @@ func (s *IdeasClient) HandlePostIdeas(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// validatePostIdeaRequest normalizes the tags of req and resolves its status:
+// ideas with a PublishAt in the future, and drafts with a PublishAt, are
+// scheduled.
+func validatePostIdeaRequest(req *ideas.PostIdeaRequest, now time.Time) error {
+	var err error
+	req.Tags, err = normalizeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+
+	switch req.Status {
+	case "", ideas.StatusPublished:
+		req.Status = ideas.StatusPublished
+		if req.PublishAt.After(now) {
+			req.Status = ideas.StatusScheduled
+		}
+	case ideas.StatusDraft:
+		if !req.PublishAt.IsZero() {
+			req.Status = ideas.StatusScheduled
+		}
+	default:
+		return fmt.Errorf("status must be %q or %q", ideas.StatusDraft, ideas.StatusPublished)
+	}
+	return nil
+}
+
 func parsePostIdeaRequest(r *http.Request) (*ideas.PostIdeaRequest, error) {
 	var req ideas.PostIdeaRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
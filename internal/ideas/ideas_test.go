@@ -0,0 +1,50 @@
+package ideas
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+func TestLastModified(t *testing.T) {
+	posted := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := posted.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		ideas []*ideas.Idea
+		want  time.Time
+	}{
+		{
+			name: "no ideas",
+		},
+		{
+			name:  "newest published idea",
+			ideas: []*ideas.Idea{{Time: posted}, {Time: posted.Add(-time.Hour), Status: ideas.StatusPublished}},
+			want:  posted,
+		},
+		{
+			name:  "deleted idea",
+			ideas: []*ideas.Idea{{Time: posted}, {Time: posted.Add(-time.Hour), DeletedAt: later}},
+			want:  later,
+		},
+		{
+			name: "drafts and scheduled ideas",
+			ideas: []*ideas.Idea{
+				{Time: posted},
+				{Time: later, Status: ideas.StatusScheduled},
+				{Time: posted, Status: ideas.StatusDraft, DeletedAt: later},
+			},
+			want: posted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastModified(tt.ideas); !got.Equal(tt.want) {
+				t.Errorf("lastModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
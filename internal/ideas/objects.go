@@ -0,0 +1,360 @@
+package ideas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/storage"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+const (
+	// ideaObjectPrefix starts the name of the object of every idea, followed
+	// by its ID and ideaObjectSuffix.
+	ideaObjectPrefix = "by-id/"
+	ideaObjectSuffix = ".json"
+
+	// indexObject holds a copy of every idea, rewritten by compactions.
+	indexObject = "index.json"
+)
+
+// IdeasObjectsClient stores every idea in its own object, in a GCS bucket or
+// a local directory, so posting an idea writes a single small object no
+// matter how many ideas there are.
+//
+// Reading every idea object would get slower as ideas grow, so an index with
+// a copy of all of them is compacted every now and then. Reads list the idea
+// objects, take the ones whose generation didn't change since the last
+// compaction from the index and only fetch the others.
+type IdeasObjectsClient struct {
+	store storage.GenerationStore
+
+	// lock serializes compactions, so an older index can't overwrite a newer one.
+	lock sync.Mutex
+}
+
+// objectsIndex is the content of indexObject.
+type objectsIndex struct {
+	Ideas []*indexEntry `json:"ideas"`
+}
+
+// indexEntry is an idea in the index, with the generation of its object.
+type indexEntry struct {
+	Generation int64       `json:"generation"`
+	Idea       *ideas.Idea `json:"idea"`
+}
+
+func NewIdeasObjectsClient(cfg config.Ideas) (*IdeasObjectsClient, error) {
+	var store storage.GenerationStore
+	var err error
+	if cfg.Backend == config.IdeasBackendFilesystem {
+		store, err = storage.NewFileStore(cfg.Path)
+	} else {
+		store, err = storage.NewGCSStore(cfg.Bucket, cfg.Prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdeasObjectsClient{store: store}, nil
+}
+
+// GetIdeas fetches all the ideas from the index and the objects that changed
+// since it was compacted.
+func (i *IdeasObjectsClient) GetIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error) {
+	index, _, err := i.loadIndex(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read ideas objects", "err", err)
+		return nil, err
+	}
+
+	allIdeas := make([]*ideas.Idea, 0, len(index.Ideas))
+	for _, entry := range index.Ideas {
+		allIdeas = append(allIdeas, entry.Idea)
+	}
+
+	return &ideas.GetIdeasResponse{
+		Ideas: prepareIdeas(req, allIdeas),
+	}, nil
+}
+
+// CheckStorage tells whether the bucket or directory is reachable.
+func (i *IdeasObjectsClient) CheckStorage(ctx context.Context) error {
+	return i.store.Check(ctx)
+}
+
+// PostIdea writes the object of the new idea, leaving every other idea alone.
+// The object must not exist yet, so an idea with the same ID isn't replaced.
+func (i *IdeasObjectsClient) PostIdea(ctx context.Context, req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error) {
+	idea := idea(req)
+	err := i.writeIdea(ctx, idea, 0)
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		return nil, ErrIdeaExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ideas.PostIdeaResponse{
+		Idea: idea,
+	}, nil
+}
+
+// UpdateIdea applies update to the object of the idea with id, and
+// overwrites it only if it's still the generation that was read.
+func (i *IdeasObjectsClient) UpdateIdea(ctx context.Context, id string, update func(*ideas.Idea) error) (*ideas.Idea, error) {
+	for attempt := 1; ; attempt++ {
+		entry, err := i.readIdea(ctx, ideaObject(id))
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrIdeaNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := update(entry.Idea); err != nil {
+			return nil, err
+		}
+
+		err = i.writeIdea(ctx, entry.Idea, entry.Generation)
+		if err == nil {
+			return entry.Idea, nil
+		}
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrIdeaNotFound
+		}
+		if !errors.Is(err, storage.ErrPreconditionFailed) || attempt == maxWriteAttempts {
+			return nil, err
+		}
+	}
+}
+
+// DeleteIdea deletes the object of the idea with id, only if it's still the
+// generation that was checked. The index keeps a copy until the next
+// compaction, but reads skip ideas without an object.
+func (i *IdeasObjectsClient) DeleteIdea(ctx context.Context, id string, check func(*ideas.Idea) error) error {
+	for attempt := 1; ; attempt++ {
+		entry, err := i.readIdea(ctx, ideaObject(id))
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrIdeaNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := check(entry.Idea); err != nil {
+			return err
+		}
+
+		err = i.store.Delete(ctx, ideaObject(id), entry.Generation)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrIdeaNotFound
+		}
+		if !errors.Is(err, storage.ErrPreconditionFailed) || attempt == maxWriteAttempts {
+			return err
+		}
+	}
+}
+
+// ImportIdeas writes the objects of allIdeas, e.g. to migrate them from the
+// single layout. Ideas that are already stored are overwritten.
+func (i *IdeasObjectsClient) ImportIdeas(ctx context.Context, allIdeas []*ideas.Idea) error {
+	fillIDs(allIdeas)
+	for _, idea := range allIdeas {
+		if err := i.writeIdea(ctx, idea, storage.AnyGeneration); err != nil {
+			return fmt.Errorf("failed to import idea %s: %v", idea.ID, err)
+		}
+	}
+	return nil
+}
+
+// Compact rewrites the index with every idea, if any changed since the last
+// compaction.
+func (i *IdeasObjectsClient) Compact(ctx context.Context) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	index, changed, err := i.loadIndex(ctx)
+	if err != nil || !changed {
+		return err
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	_, err = i.store.Write(ctx, indexObject, data, storage.AnyGeneration)
+	return err
+}
+
+// loadIndex reads the index, and brings it up to date with the idea objects.
+// changed tells whether any idea was posted, updated or deleted since the
+// index was compacted.
+func (i *IdeasObjectsClient) loadIndex(ctx context.Context) (index *objectsIndex, changed bool, err error) {
+	generations, err := i.store.ListGenerations(ctx, ideaObjectPrefix)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list ideas objects: %v", err)
+	}
+
+	compacted, err := i.readIndex(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	index = &objectsIndex{Ideas: make([]*indexEntry, 0, len(generations))}
+	for _, entry := range compacted.Ideas {
+		name := ideaObject(entry.Idea.ID)
+
+		generation, ok := generations[name]
+		if !ok {
+			// The idea was deleted.
+			changed = true
+			continue
+		}
+
+		if generation == entry.Generation {
+			index.Ideas = append(index.Ideas, entry)
+			delete(generations, name)
+		}
+	}
+
+	// What's left was posted or updated after the compaction.
+	for name := range generations {
+		changed = true
+
+		entry, err := i.readIdea(ctx, name)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			// The idea was deleted after listing.
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		index.Ideas = append(index.Ideas, entry)
+	}
+
+	return index, changed, nil
+}
+
+// readIndex returns the compacted index, empty if there was no compaction yet.
+func (i *IdeasObjectsClient) readIndex(ctx context.Context) (*objectsIndex, error) {
+	index := &objectsIndex{}
+
+	data, _, err := i.store.ReadGeneration(ctx, indexObject)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ideas index: %v", err)
+	}
+
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ideas index: %v", err)
+	}
+	return index, nil
+}
+
+func (i *IdeasObjectsClient) readIdea(ctx context.Context, name string) (*indexEntry, error) {
+	data, generation, err := i.store.ReadGeneration(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &indexEntry{Generation: generation}
+	if err := json.Unmarshal(data, &entry.Idea); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idea %s: %v", name, err)
+	}
+	if entry.Idea == nil {
+		return nil, fmt.Errorf("idea %s is empty", name)
+	}
+
+	// The ID is the name of the object, whatever its content says.
+	entry.Idea.ID = strings.TrimSuffix(strings.TrimPrefix(name, ideaObjectPrefix), ideaObjectSuffix)
+	return entry, nil
+}
+
+// writeIdea writes the object of idea, if it's at ifGeneration.
+func (i *IdeasObjectsClient) writeIdea(ctx context.Context, idea *ideas.Idea, ifGeneration int64) error {
+	data, err := json.Marshal(idea)
+	if err != nil {
+		return err
+	}
+
+	_, err = i.store.Write(ctx, ideaObject(idea.ID), data, ifGeneration)
+	return err
+}
+
+// ideaObject is the name of the object of the idea with id.
+func ideaObject(id string) string {
+	return ideaObjectPrefix + id + ideaObjectSuffix
+}
+
+// Compactor is an IdeasRepository that periodically compacts what it stores.
+type Compactor interface {
+	Compact(ctx context.Context) error
+}
+
+// RunCompactor compacts the repository every interval until ctx is done. It
+// returns right away if the repository doesn't need compactions.
+func (s *IdeasClient) RunCompactor(ctx context.Context) {
+	compactor, ok := s.ideasRepo.(Compactor)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(s.compactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		if err := compactor.Compact(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to compact ideas", "err", err)
+			continue
+		}
+		slog.DebugContext(ctx, "compacted ideas", "duration", time.Since(start))
+	}
+}
+
+// MigrateToObjects copies every idea stored with the single layout of from to
+// the objects layout of to, and compacts its index. It returns how many ideas
+// were copied.
+func MigrateToObjects(ctx context.Context, from, to config.Ideas) (int, error) {
+	from.Layout = config.IdeasLayoutSingle
+	source, err := newIdeasRepository(from)
+	if err != nil {
+		return 0, err
+	}
+
+	destination, err := NewIdeasObjectsClient(to)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := source.GetIdeas(ctx, &ideas.GetIdeasRequest{Offset: 0, Limit: math.MaxInt})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ideas: %v", err)
+	}
+
+	if err := destination.ImportIdeas(ctx, response.Ideas); err != nil {
+		return 0, err
+	}
+	if err := destination.Compact(ctx); err != nil {
+		return 0, fmt.Errorf("failed to compact ideas: %v", err)
+	}
+	return len(response.Ideas), nil
+}
@@ -0,0 +1,121 @@
+package ideas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/storage"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+func TestObjectsNewIdeaKeepsExistingOne(t *testing.T) {
+	repo, err := NewIdeasObjectsClient(config.Ideas{Backend: config.IdeasBackendFilesystem, Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	stored := storedIdeas(1)
+	if err := repo.ImportIdeas(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	// New ideas are written like PostIdea does, with the ID of the stored one.
+	err = repo.writeIdea(ctx, &ideas.Idea{ID: stored[0].ID, Idea: "Another idea."}, 0)
+	if !errors.Is(err, storage.ErrPreconditionFailed) {
+		t.Fatalf("writeIdea() error = %v, want %v", err, storage.ErrPreconditionFailed)
+	}
+
+	response, err := repo.GetIdeas(ctx, &ideas.GetIdeasRequest{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Ideas) != 1 || response.Ideas[0].Idea != stored[0].Idea {
+		t.Errorf("GetIdeas() = %+v, want the stored idea untouched", response.Ideas)
+	}
+}
+
+// BenchmarkPostIdea measures posting an idea with the single and the objects
+// layouts of the filesystem backend, as the number of stored ideas grows. The
+// single layout keeps no revisions here, as the objects layout has none.
+func BenchmarkPostIdea(b *testing.B) {
+	layouts := []struct {
+		name string
+		seed func(b *testing.B, dir string, stored []*ideas.Idea) IdeasRepository
+	}{
+		{name: config.IdeasLayoutSingle, seed: seedSingle},
+		{name: config.IdeasLayoutObjects, seed: seedObjects},
+	}
+
+	for _, size := range []int{100, 1000, 10000} {
+		for _, layout := range layouts {
+			b.Run(fmt.Sprintf("%s/ideas=%d", layout.name, size), func(b *testing.B) {
+				repo := layout.seed(b, b.TempDir(), storedIdeas(size))
+				ctx := context.Background()
+
+				for i := 0; b.Loop(); i++ {
+					_, err := repo.PostIdea(ctx, &ideas.PostIdeaRequest{
+						Idea:   fmt.Sprintf("Posted idea number %d.", i),
+						Status: ideas.StatusPublished,
+					})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// seedSingle writes stored to a single file, without revisions.
+func seedSingle(b *testing.B, dir string, stored []*ideas.Idea) IdeasRepository {
+	repo := &IdeasFileClient{path: filepath.Join(dir, "ideas.json")}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(repo.path, data, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return repo
+}
+
+// seedObjects writes stored to a file per idea, and compacts their index.
+func seedObjects(b *testing.B, dir string, stored []*ideas.Idea) IdeasRepository {
+	repo, err := NewIdeasObjectsClient(config.Ideas{Backend: config.IdeasBackendFilesystem, Path: dir})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := repo.ImportIdeas(ctx, stored); err != nil {
+		b.Fatal(err)
+	}
+	if err := repo.Compact(ctx); err != nil {
+		b.Fatal(err)
+	}
+	return repo
+}
+
+// storedIdeas returns size ideas of a typical length, a minute apart.
+func storedIdeas(size int) []*ideas.Idea {
+	stored := make([]*ideas.Idea, size)
+	start := time.Now().Add(-time.Duration(size) * time.Minute)
+	for i := range stored {
+		stored[i] = &ideas.Idea{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Idea:   fmt.Sprintf("Stored idea number %d, about as long as most of my ideas are. #bench", i),
+			Status: ideas.StatusPublished,
+			Tags:   []string{"bench"},
+		}
+	}
+	return stored
+}
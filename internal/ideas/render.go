@@ -0,0 +1,23 @@
+package ideas
+
+import (
+	"github.com/jaehnri/website-backend/internal/markdown"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// renderIdeas returns copies of ideas with their rendered HTML. Ideas are
+// shared by cached snapshots, so they're never modified.
+func renderIdeas(source []*ideas.Idea) ([]*ideas.Idea, error) {
+	rendered := make([]*ideas.Idea, 0, len(source))
+	for _, idea := range source {
+		html, err := markdown.Render(idea.Idea)
+		if err != nil {
+			return nil, err
+		}
+
+		renderedIdea := *idea
+		renderedIdea.HTML = html
+		rendered = append(rendered, &renderedIdea)
+	}
+	return rendered, nil
+}
@@ -0,0 +1,146 @@
+package ideas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+const (
+	RevisionsPath       = "/ideas/revisions"
+	RevisionPath        = "/ideas/revisions/{gen}"
+	RestoreRevisionPath = "/ideas/revisions/{gen}/restore"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// RevisionRepository is an IdeasRepository that keeps past versions of the
+// stored ideas.
+type RevisionRepository interface {
+	// ListRevisions returns every revision, newest first.
+	ListRevisions(ctx context.Context) ([]*ideas.Revision, error)
+
+	// GetRevision returns a revision and its content, or ErrRevisionNotFound.
+	GetRevision(ctx context.Context, generation int64) (*ideas.Revision, []byte, error)
+
+	// RestoreRevision makes the content of a revision current, creating a new
+	// revision. It returns ErrRevisionNotFound if there's no such revision.
+	RestoreRevision(ctx context.Context, generation int64) error
+}
+
+// HandleGetRevisions receives an HTTP request and returns the revisions of the
+// stored ideas in GetRevisionsResponse format.
+func (s *IdeasClient) HandleGetRevisions(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.revisionRepository(w)
+	if !ok {
+		return
+	}
+
+	revisions, err := repo.ListRevisions(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list revisions", "err", err)
+		http.Error(w, "failed to list revisions", http.StatusInternalServerError)
+		return
+	}
+
+	writePrivateJSON(w, &ideas.GetRevisionsResponse{Revisions: revisions})
+}
+
+// HandleGetRevision receives an HTTP request and returns every idea of a
+// revision in GetRevisionResponse format.
+func (s *IdeasClient) HandleGetRevision(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.revisionRepository(w)
+	if !ok {
+		return
+	}
+
+	generation, err := parseGeneration(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revision, data, err := repo.GetRevision(r.Context(), generation)
+	if errors.Is(err, ErrRevisionNotFound) {
+		http.Error(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to read revision", "generation", generation, "err", err)
+		http.Error(w, "failed to read revision", http.StatusInternalServerError)
+		return
+	}
+
+	writePrivateJSON(w, &ideas.GetRevisionResponse{
+		Revision: revision,
+		Ideas:    parseIdeas(&ideas.GetIdeasRequest{Offset: 0, Limit: math.MaxInt}, data),
+	})
+}
+
+// HandleRestoreRevision receives an HTTP request and restores every idea to a
+// revision. The current ideas aren't lost, as restoring creates a new revision.
+func (s *IdeasClient) HandleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.revisionRepository(w)
+	if !ok {
+		return
+	}
+
+	generation, err := parseGeneration(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = repo.RestoreRevision(r.Context(), generation)
+	if errors.Is(err, ErrRevisionNotFound) {
+		http.Error(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to restore revision", "generation", generation, "err", err)
+		http.Error(w, "failed to restore revision", http.StatusInternalServerError)
+		return
+	}
+
+	// Anything may have changed, so ideas are loaded and indexed again.
+	s.cache.Clear()
+	slog.InfoContext(r.Context(), "restored ideas revision", "generation", generation)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revisionRepository returns the repository if it keeps revisions, or responds
+// with 501 Not Implemented.
+func (s *IdeasClient) revisionRepository(w http.ResponseWriter) (RevisionRepository, bool) {
+	repo, ok := s.ideasRepo.(RevisionRepository)
+	if !ok {
+		http.Error(w, "the ideas backend doesn't keep revisions", http.StatusNotImplemented)
+	}
+	return repo, ok
+}
+
+func parseGeneration(r *http.Request) (int64, error) {
+	generation, err := strconv.ParseInt(r.PathValue("gen"), 10, 64)
+	if err != nil || generation <= 0 {
+		return 0, errors.New("gen must be a positive number")
+	}
+	return generation, nil
+}
+
+// writePrivateJSON writes v as a JSON response that must not be cached.
+func writePrivateJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
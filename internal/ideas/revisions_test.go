@@ -0,0 +1,107 @@
+package ideas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// serveRevision calls handler for the revision gen, and decodes its JSON
+// response into v, if any.
+func serveRevision(t *testing.T, handler http.HandlerFunc, gen string, v any) int {
+	t.Helper()
+
+	r := httptest.NewRequest("GET", "/ideas/revisions/"+gen, nil)
+	r.SetPathValue("gen", gen)
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	if v != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code
+}
+
+func TestRevisions(t *testing.T) {
+	repo := NewIdeasFileClient(config.Ideas{Path: filepath.Join(t.TempDir(), "ideas.json"), MaxRevisions: 10})
+	postIdeas(t, repo, "one", "two", "three")
+	client, _ := newTestClient(repo)
+
+	var list ideas.GetRevisionsResponse
+	if code := serveRevision(t, client.HandleGetRevisions, "", &list); code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", code, http.StatusOK)
+	}
+	if len(list.Revisions) != 3 {
+		t.Fatalf("%d revisions, want 3", len(list.Revisions))
+	}
+	for i, revision := range list.Revisions {
+		if revision.Current != (i == 0) {
+			t.Errorf("revision %d: current = %v, want only the newest", i, revision.Current)
+		}
+		if i > 0 && revision.Generation >= list.Revisions[i-1].Generation {
+			t.Errorf("revisions aren't sorted newest first: %d after %d", revision.Generation, list.Revisions[i-1].Generation)
+		}
+	}
+
+	// The oldest revision has the first idea only.
+	oldest := strconv.FormatInt(list.Revisions[2].Generation, 10)
+	var revision ideas.GetRevisionResponse
+	if code := serveRevision(t, client.HandleGetRevision, oldest, &revision); code != http.StatusOK {
+		t.Fatalf("get status = %d, want %d", code, http.StatusOK)
+	}
+	if got := ideaTexts(revision.Ideas); !reflect.DeepEqual(got, []string{"one"}) {
+		t.Errorf("ideas of the oldest revision = %q, want [one]", got)
+	}
+
+	snapshotTexts(t, client)
+	if code := serveRevision(t, client.HandleRestoreRevision, oldest, nil); code != http.StatusNoContent {
+		t.Fatalf("restore status = %d, want %d", code, http.StatusNoContent)
+	}
+
+	// Restoring serves the revision right away, and keeps the ideas it replaced.
+	if published, _ := snapshotTexts(t, client); !reflect.DeepEqual(published, []string{"one"}) {
+		t.Errorf("published ideas after restoring = %q, want [one]", published)
+	}
+	list = ideas.GetRevisionsResponse{}
+	serveRevision(t, client.HandleGetRevisions, "", &list)
+	if len(list.Revisions) != 4 {
+		t.Errorf("%d revisions after restoring, want 4", len(list.Revisions))
+	}
+}
+
+func TestRevisionErrors(t *testing.T) {
+	repo := NewIdeasFileClient(config.Ideas{Path: filepath.Join(t.TempDir(), "ideas.json"), MaxRevisions: 10})
+	postIdeas(t, repo, "one")
+	client, _ := newTestClient(repo)
+	sqliteClient, _ := newTestClient(newTestSQLite(t))
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		gen     string
+		want    int
+	}{
+		{name: "invalid generation", handler: client.HandleGetRevision, gen: "latest", want: http.StatusBadRequest},
+		{name: "negative generation", handler: client.HandleRestoreRevision, gen: "-1", want: http.StatusBadRequest},
+		{name: "unknown revision", handler: client.HandleGetRevision, gen: "1", want: http.StatusNotFound},
+		{name: "restore unknown revision", handler: client.HandleRestoreRevision, gen: "1", want: http.StatusNotFound},
+		{name: "backend without revisions", handler: sqliteClient.HandleGetRevisions, want: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := serveRevision(t, tt.handler, tt.gen, nil); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package ideas
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/httpcache"
+	"github.com/jaehnri/website-backend/internal/search"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// serveSearch returns a page of the ideas matching req.Query in
+// SearchIdeasResponse format, most relevant first.
+func (s *IdeasClient) serveSearch(w http.ResponseWriter, r *http.Request, req *ideas.GetIdeasRequest) {
+	query := search.ParseQuery(req.Query)
+	if query.IsEmpty() {
+		http.Error(w, "q must contain at least one word", http.StatusBadRequest)
+		return
+	}
+
+	// Searches of repositories only carry an ETag, like serveQuery.
+	var response *ideas.SearchIdeasResponse
+	var lastModified time.Time
+	if searcher, ok := s.ideasRepo.(IdeasSearcher); ok {
+		var err error
+		response, err = searcher.SearchIdeas(r.Context(), req)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to search ideas", "err", err)
+			http.Error(w, "failed to search my ideas", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		snapshot, err := s.getIdeas(r.Context())
+		if err != nil {
+			http.Error(w, "failed to fetch my ideas", http.StatusInternalServerError)
+			return
+		}
+		response = pageMatches(searchIdeas(snapshot, query, req), req)
+		lastModified = snapshot.lastModified
+	}
+
+	// Only the ideas of the page are highlighted and rendered.
+	for _, match := range response.Ideas {
+		match.Highlight = search.Highlight(match.Idea.Idea, query)
+
+		if req.Render == ideas.RenderHTML {
+			rendered, err := renderIdeas([]*ideas.Idea{match.Idea})
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to render ideas", "err", err)
+				http.Error(w, "failed to render ideas", http.StatusInternalServerError)
+				return
+			}
+			match.Idea = rendered[0]
+		}
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+
+	httpcache.ServeJSONModified(w, r, body, s.cacheControl, lastModified)
+}
+
+// searchIdeas returns the ideas of snapshot matching query and the filters of
+// req, most relevant first.
+func searchIdeas(snapshot *ideasSnapshot, query *search.Query, req *ideas.GetIdeasRequest) []*ideas.IdeaMatch {
+	var matches []*ideas.IdeaMatch
+	for _, match := range snapshot.index.Search(query) {
+		// The index may know about ideas posted after the snapshot was taken.
+		idea, exists := snapshot.byID[match.ID]
+		if !exists || !matchesFilters(idea, req) {
+			continue
+		}
+
+		matches = append(matches, &ideas.IdeaMatch{Idea: idea, Score: match.Score})
+	}
+	return matches
+}
+
+// pageMatches returns the page of matches of req.
+func pageMatches(matches []*ideas.IdeaMatch, req *ideas.GetIdeasRequest) *ideas.SearchIdeasResponse {
+	response := &ideas.SearchIdeasResponse{
+		Ideas: []*ideas.IdeaMatch{},
+		Total: len(matches),
+	}
+	if req.Offset >= 0 && req.Offset < len(matches) && req.Limit > 0 {
+		matches = matches[req.Offset:]
+		response.Ideas = matches[:min(req.Limit, len(matches))]
+	}
+	return response
+}
@@ -0,0 +1,146 @@
+package ideas
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/search"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// searchTexts returns the texts of the ideas of the snapshot of client
+// matching q and req, most relevant first.
+func searchTexts(t *testing.T, client *IdeasClient, q string, req *ideas.GetIdeasRequest) []string {
+	t.Helper()
+
+	snapshot, err := client.getIdeas(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{}
+	for _, match := range searchIdeas(snapshot, search.ParseQuery(q), req) {
+		texts = append(texts, match.Idea.Idea)
+	}
+	return texts
+}
+
+func TestSearchIndexFollowsSnapshot(t *testing.T) {
+	repo := NewIdeasFileClient(config.Ideas{Path: filepath.Join(t.TempDir(), "ideas.json"), MaxRevisions: 10})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	postIdea(t, repo, "Go is fun", ideas.StatusPublished, now.Add(-time.Hour))
+	postIdea(t, repo, "Go drafts are private", ideas.StatusDraft, now)
+
+	client, _ := newTestClient(repo)
+	req := &ideas.GetIdeasRequest{}
+	if got, want := searchTexts(t, client, "go", req), []string{"Go is fun"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search = %q, want %q, without drafts", got, want)
+	}
+
+	// Ideas posted by another replica are found once the snapshot is reloaded.
+	other := postIdea(t, repo, "Go from another replica", ideas.StatusPublished, now)
+	if got := searchTexts(t, client, "replica", req); len(got) != 0 {
+		t.Errorf("search = %q, want nothing before reloading", got)
+	}
+	client.cache.Clear()
+	if got, want := searchTexts(t, client, "replica", req), []string{"Go from another replica"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search after reloading = %q, want %q", got, want)
+	}
+
+	// Ideas posted or changed here are indexed right away.
+	posted := postIdea(t, repo, "Go posted here", ideas.StatusPublished, now)
+	client.applyIdea(posted)
+	if got, want := searchTexts(t, client, "posted", req), []string{"Go posted here"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search after posting = %q, want %q", got, want)
+	}
+
+	deleted := *other
+	deleted.DeletedAt = now
+	client.applyIdea(&deleted)
+	if got := searchTexts(t, client, "replica", req); len(got) != 0 {
+		t.Errorf("search after deleting = %q, want nothing", got)
+	}
+
+	client.applyIdea(other)
+	client.forgetIdea(other.ID)
+	if got := searchTexts(t, client, "replica", req); len(got) != 0 {
+		t.Errorf("search after purging = %q, want nothing", got)
+	}
+}
+
+func TestSearchIdeasFilters(t *testing.T) {
+	repo := newTestSQLite(t)
+	postIdeas(t, repo, "Go #generics", "Go #errors", "Go and #generics again")
+
+	client, _ := newTestClient(repo)
+
+	tests := []struct {
+		name string
+		req  *ideas.GetIdeasRequest
+		want []string
+	}{
+		{
+			name: "no filters",
+			req:  &ideas.GetIdeasRequest{},
+			want: []string{"Go #errors", "Go #generics", "Go and #generics again"},
+		},
+		{
+			name: "tag",
+			req:  &ideas.GetIdeasRequest{Tag: "generics"},
+			want: []string{"Go #generics", "Go and #generics again"},
+		},
+		{
+			name: "since",
+			req:  &ideas.GetIdeasRequest{Since: time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC)},
+			want: []string{"Go #errors", "Go and #generics again"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Only which ideas match is checked, their ranking is the index's.
+			if got := searchTexts(t, client, "go", tt.req); !sameElements(got, tt.want) {
+				t.Errorf("search = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageMatches(t *testing.T) {
+	var matches []*ideas.IdeaMatch
+	for _, text := range []string{"one", "two", "three"} {
+		matches = append(matches, &ideas.IdeaMatch{Idea: &ideas.Idea{Idea: text}})
+	}
+
+	tests := []struct {
+		name string
+		req  *ideas.GetIdeasRequest
+		want []string
+	}{
+		{name: "first page", req: &ideas.GetIdeasRequest{Limit: 2}, want: []string{"one", "two"}},
+		{name: "last page", req: &ideas.GetIdeasRequest{Offset: 2, Limit: 2}, want: []string{"three"}},
+		{name: "past the end", req: &ideas.GetIdeasRequest{Offset: 3, Limit: 2}, want: []string{}},
+		{name: "no limit", req: &ideas.GetIdeasRequest{Offset: 0}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := pageMatches(matches, tt.req)
+
+			got := []string{}
+			for _, match := range response.Ideas {
+				got = append(got, match.Idea.Idea)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("page = %q, want %q", got, tt.want)
+			}
+			if response.Total != len(matches) {
+				t.Errorf("total = %d, want %d", response.Total, len(matches))
+			}
+		})
+	}
+}
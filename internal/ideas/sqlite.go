@@ -0,0 +1,570 @@
+package ideas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/search"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+
+	// Registers sqliteDriver.
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// sqliteDriver is the database/sql driver of the sqlite backend. It's pure Go,
+// so the server builds without cgo.
+const sqliteDriver = "sqlite"
+
+// sqliteMigrations create and update the schema, in order. The user_version
+// of the database is how many were applied, so new ones must be appended.
+var sqliteMigrations = []string{
+	`CREATE TABLE ideas (
+		pk         INTEGER PRIMARY KEY,
+		id         TEXT NOT NULL UNIQUE,
+		time       INTEGER NOT NULL,
+		idea       TEXT NOT NULL,
+		status     TEXT NOT NULL DEFAULT '',
+		deleted_at INTEGER
+	);
+	CREATE INDEX ideas_time ON ideas (time DESC, id DESC);
+
+	CREATE TABLE idea_tags (
+		idea_pk INTEGER NOT NULL REFERENCES ideas (pk) ON DELETE CASCADE,
+		tag     TEXT NOT NULL,
+		PRIMARY KEY (tag, idea_pk)
+	);
+	CREATE INDEX idea_tags_idea ON idea_tags (idea_pk);
+
+	CREATE VIRTUAL TABLE ideas_fts USING fts5 (
+		idea,
+		content = 'ideas',
+		content_rowid = 'pk',
+		tokenize = 'unicode61 remove_diacritics 2'
+	);
+	CREATE TRIGGER ideas_fts_insert AFTER INSERT ON ideas BEGIN
+		INSERT INTO ideas_fts (rowid, idea) VALUES (new.pk, new.idea);
+	END;
+	CREATE TRIGGER ideas_fts_delete AFTER DELETE ON ideas BEGIN
+		INSERT INTO ideas_fts (ideas_fts, rowid, idea) VALUES ('delete', old.pk, old.idea);
+	END;
+	CREATE TRIGGER ideas_fts_update AFTER UPDATE OF idea ON ideas BEGIN
+		INSERT INTO ideas_fts (ideas_fts, rowid, idea) VALUES ('delete', old.pk, old.idea);
+		INSERT INTO ideas_fts (rowid, idea) VALUES (new.pk, new.idea);
+	END;`,
+}
+
+// sqlitePublished only keeps published ideas, like isPublished.
+const sqlitePublished = `ideas.deleted_at IS NULL AND ideas.status IN ('', '` + ideas.StatusPublished + `')`
+
+// IdeasSQLiteClient stores ideas in a SQLite database, for self-hosting.
+// Filters and searches use indexes, and the database is in WAL mode, so reads
+// don't wait for writes.
+type IdeasSQLiteClient struct {
+	db *sql.DB
+}
+
+func NewIdeasSQLiteClient(cfg config.Ideas) (*IdeasSQLiteClient, error) {
+	// Pragmas are set on every connection. Transactions take the write lock
+	// right away, so they don't fail when another one writes first.
+	dsn := cfg.Path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate"
+	db, err := sql.Open(sqliteDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ideas database %s: %v", cfg.Path, err)
+	}
+
+	client := &IdeasSQLiteClient{db: db}
+	if err := client.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate ideas database %s: %v", cfg.Path, err)
+	}
+	return client, nil
+}
+
+// migrate applies the migrations the database lacks, each in a transaction.
+func (i *IdeasSQLiteClient) migrate(ctx context.Context) error {
+	var version int
+	if err := i.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("schema version %d is newer than the %d known by this server", version, len(sqliteMigrations))
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		err := i.withTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, sqliteMigrations[version]); err != nil {
+				return err
+			}
+			// Pragmas can't take parameters.
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d: %v", version+1, err)
+		}
+	}
+	return nil
+}
+
+// GetIdeas returns a page of the ideas matching the filters of req, newest
+// first. Unlike other repositories, it honors Since, Until, Tag and Query.
+func (i *IdeasSQLiteClient) GetIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error) {
+	where, args := sqliteFilters(req)
+	if query := search.ParseQuery(req.Query); !query.IsEmpty() {
+		where = append(where, "ideas.pk IN (SELECT rowid FROM ideas_fts WHERE ideas_fts MATCH ?)")
+		args = append(args, ftsQuery(query))
+	}
+
+	var total int
+	var list []*sqliteIdea
+	err := i.withReadTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		total, err = i.countIdeas(ctx, tx, " FROM ideas"+sqliteWhere(where), args)
+		if err != nil {
+			return err
+		}
+
+		list, err = i.pageIdeas(ctx, tx, " FROM ideas"+sqliteWhere(where), sqliteNewestFirst, args, req.Limit, req.Offset, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ideas.GetIdeasResponse{
+		Ideas: ideasOf(list),
+		Total: total,
+	}, nil
+}
+
+// QueryIdeas filters published ideas with the indexes on their time and tags,
+// and pages them with the cursor or offset of req.
+func (i *IdeasSQLiteClient) QueryIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.GetIdeasResponse, error) {
+	where, args := sqliteFilters(req)
+	where = append([]string{sqlitePublished}, where...)
+	countWhere, countArgs := slices.Clone(where), slices.Clone(args)
+
+	offset := req.Offset
+	if req.Cursor != "" {
+		c, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		// Pages are sorted by time and ID, so the next one starts at the
+		// first idea older than the cursor, even if its idea is gone.
+		where = append(where, "(ideas.time < ? OR (ideas.time = ? AND ideas.id < ?))")
+		args = append(args, c.time.UnixNano(), c.time.UnixNano(), c.id)
+		offset = 0
+	}
+
+	// One more idea than the limit tells whether there's a next page.
+	limit := req.Limit
+	if limit > 0 && limit < math.MaxInt {
+		limit++
+	}
+
+	var total int
+	var list []*sqliteIdea
+	err := i.withReadTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		total, err = i.countIdeas(ctx, tx, " FROM ideas"+sqliteWhere(countWhere), countArgs)
+		if err != nil {
+			return err
+		}
+
+		list, err = i.pageIdeas(ctx, tx, " FROM ideas"+sqliteWhere(where), sqliteNewestFirst, args, limit, offset, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ideas.GetIdeasResponse{
+		Ideas: ideasOf(list),
+		Total: total,
+	}
+	if req.Limit > 0 && len(response.Ideas) > req.Limit {
+		response.Ideas = response.Ideas[:req.Limit]
+		response.NextCursor = encodeCursor(response.Ideas[len(response.Ideas)-1])
+	}
+	return response, nil
+}
+
+// SearchIdeas uses the full-text index of ideas, ranking them with BM25.
+func (i *IdeasSQLiteClient) SearchIdeas(ctx context.Context, req *ideas.GetIdeasRequest) (*ideas.SearchIdeasResponse, error) {
+	where, args := sqliteFilters(req)
+	where = append([]string{"ideas_fts MATCH ?", sqlitePublished}, where...)
+	args = append([]any{ftsQuery(search.ParseQuery(req.Query))}, args...)
+
+	from := " FROM ideas_fts JOIN ideas ON ideas.pk = ideas_fts.rowid" + sqliteWhere(where)
+
+	var total int
+	var list []*sqliteIdea
+	err := i.withReadTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		total, err = i.countIdeas(ctx, tx, from, args)
+		if err != nil {
+			return err
+		}
+
+		list, err = i.pageIdeas(ctx, tx, from, "bm25(ideas_fts), "+sqliteNewestFirst, args, req.Limit, req.Offset, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ideas.SearchIdeasResponse{
+		Ideas: make([]*ideas.IdeaMatch, 0, len(list)),
+		Total: total,
+	}
+	for _, row := range list {
+		response.Ideas = append(response.Ideas, &ideas.IdeaMatch{Idea: row.idea, Score: row.score})
+	}
+	return response, nil
+}
+
+// sqliteNewestFirst sorts ideas like pages of cursors expect.
+const sqliteNewestFirst = "ideas.time DESC, ideas.id DESC"
+
+// countIdeas counts the ideas of from, a FROM clause with its conditions.
+func (i *IdeasSQLiteClient) countIdeas(ctx context.Context, tx *sql.Tx, from string, args []any) (int, error) {
+	var total int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count ideas: %v", err)
+	}
+	return total, nil
+}
+
+// pageIdeas returns limit ideas of from, a FROM clause with its conditions,
+// sorted by orderBy and starting at offset. Searches also get the BM25 score
+// of ideas if withScore.
+func (i *IdeasSQLiteClient) pageIdeas(ctx context.Context, tx *sql.Tx, from, orderBy string, args []any, limit, offset int, withScore bool) ([]*sqliteIdea, error) {
+	// SQLite takes negative limits as no limit at all.
+	if limit <= 0 || offset < 0 {
+		return nil, nil
+	}
+
+	columns := "ideas.pk, ideas.id, ideas.time, ideas.idea, ideas.status, ideas.deleted_at"
+	if withScore {
+		columns += ", -bm25(ideas_fts)"
+	}
+	order := " ORDER BY " + orderBy + " LIMIT ? OFFSET ?"
+	args = append(slices.Clone(args), limit, offset)
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+columns+from+order, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ideas: %v", err)
+	}
+
+	list, err := scanIdeas(rows, withScore)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tags are fetched with the same query, instead of listing the pks of
+	// the page, which may be more than SQLite accepts as parameters. Both
+	// queries see the same snapshot of the database, as tx is a transaction.
+	if err := i.fillTags(ctx, tx, list, "SELECT ideas.pk"+from+order, args); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CheckStorage pings the database.
+func (i *IdeasSQLiteClient) CheckStorage(ctx context.Context) error {
+	return i.db.PingContext(ctx)
+}
+
+func (i *IdeasSQLiteClient) PostIdea(ctx context.Context, req *ideas.PostIdeaRequest) (*ideas.PostIdeaResponse, error) {
+	idea := idea(req)
+
+	err := i.withTx(ctx, func(tx *sql.Tx) error {
+		// Transactions hold the write lock, so no other can insert the ID
+		// between the check and the insert.
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM ideas WHERE id = ?)", idea.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrIdeaExists
+		}
+
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO ideas (id, time, idea, status, deleted_at) VALUES (?, ?, ?, ?, ?)",
+			idea.ID, idea.Time.UnixNano(), idea.Idea, idea.Status, nullTime(idea.DeletedAt))
+		if err != nil {
+			return err
+		}
+
+		pk, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return insertTags(ctx, tx, pk, idea.Tags)
+	})
+	if errors.Is(err, ErrIdeaExists) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert idea: %v", err)
+	}
+
+	return &ideas.PostIdeaResponse{
+		Idea: idea,
+	}, nil
+}
+
+// UpdateIdea applies update to the stored idea with id. Transactions take
+// the write lock when they begin, so the idea can't change in between.
+func (i *IdeasSQLiteClient) UpdateIdea(ctx context.Context, id string, update func(*ideas.Idea) error) (*ideas.Idea, error) {
+	var updated *ideas.Idea
+	err := i.withTx(ctx, func(tx *sql.Tx) error {
+		stored, err := getIdea(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := update(stored.idea); err != nil {
+			return err
+		}
+		updated = stored.idea
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE ideas SET time = ?, idea = ?, status = ?, deleted_at = ? WHERE pk = ?",
+			updated.Time.UnixNano(), updated.Idea, updated.Status, nullTime(updated.DeletedAt), stored.pk)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM idea_tags WHERE idea_pk = ?", stored.pk); err != nil {
+			return err
+		}
+		return insertTags(ctx, tx, stored.pk, updated.Tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteIdea removes the stored idea with id for good.
+func (i *IdeasSQLiteClient) DeleteIdea(ctx context.Context, id string, check func(*ideas.Idea) error) error {
+	return i.withTx(ctx, func(tx *sql.Tx) error {
+		stored, err := getIdea(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := check(stored.idea); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM idea_tags WHERE idea_pk = ?", stored.pk); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM ideas WHERE pk = ?", stored.pk)
+		return err
+	})
+}
+
+// getIdea returns the stored idea with id, with its tags, or ErrIdeaNotFound.
+func getIdea(ctx context.Context, tx *sql.Tx, id string) (*sqliteIdea, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT pk, id, time, idea, status, deleted_at FROM ideas WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := scanIdeas(rows, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrIdeaNotFound
+	}
+	stored := list[0]
+
+	tags, err := tx.QueryContext(ctx, "SELECT tag FROM idea_tags WHERE idea_pk = ? ORDER BY rowid", stored.pk)
+	if err != nil {
+		return nil, err
+	}
+	defer tags.Close()
+
+	for tags.Next() {
+		var tag string
+		if err := tags.Scan(&tag); err != nil {
+			return nil, err
+		}
+		stored.idea.Tags = append(stored.idea.Tags, tag)
+	}
+	return stored, tags.Err()
+}
+
+// withTx runs f in a transaction, committed if f succeeds.
+func (i *IdeasSQLiteClient) withTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	return runTx(ctx, i.db, nil, f)
+}
+
+// withReadTx runs f in a read-only transaction, so all its queries see the
+// same snapshot of the database. Unlike other transactions, it doesn't take
+// the write lock, so it doesn't wait for writes.
+func (i *IdeasSQLiteClient) withReadTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	return runTx(ctx, i.db, &sql.TxOptions{ReadOnly: true}, f)
+}
+
+// runTx runs f in a transaction of db with opts, committed if f succeeds.
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteIdea is an idea with its primary key, and its score in searches.
+type sqliteIdea struct {
+	pk    int64
+	idea  *ideas.Idea
+	score float64
+}
+
+// scanIdeas reads the ideas of rows, whose columns are the pk, id, time, idea,
+// status and deleted_at, followed by the score if withScore.
+func scanIdeas(rows *sql.Rows, withScore bool) ([]*sqliteIdea, error) {
+	defer rows.Close()
+
+	var list []*sqliteIdea
+	for rows.Next() {
+		row := &sqliteIdea{idea: &ideas.Idea{}}
+
+		var postedAt int64
+		var deletedAt sql.NullInt64
+		dest := []any{&row.pk, &row.idea.ID, &postedAt, &row.idea.Idea, &row.idea.Status, &deletedAt}
+		if withScore {
+			dest = append(dest, &row.score)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan idea: %v", err)
+		}
+
+		row.idea.Time = time.Unix(0, postedAt).UTC()
+		if deletedAt.Valid {
+			row.idea.DeletedAt = time.Unix(0, deletedAt.Int64).UTC()
+		}
+		list = append(list, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ideas: %v", err)
+	}
+	return list, nil
+}
+
+// fillTags sets the tags of the ideas of list, whose pks are selected by
+// query with args.
+func (i *IdeasSQLiteClient) fillTags(ctx context.Context, tx *sql.Tx, list []*sqliteIdea, query string, args []any) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	byPK := make(map[int64]*ideas.Idea, len(list))
+	for _, row := range list {
+		byPK[row.pk] = row.idea
+	}
+
+	// Tags are sorted by rowid, the order they were given in.
+	rows, err := tx.QueryContext(ctx, "SELECT idea_pk, tag FROM idea_tags WHERE idea_pk IN ("+query+") ORDER BY rowid", args...)
+	if err != nil {
+		return fmt.Errorf("failed to query tags: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pk int64
+		var tag string
+		if err := rows.Scan(&pk, &tag); err != nil {
+			return fmt.Errorf("failed to scan tag: %v", err)
+		}
+
+		byPK[pk].Tags = append(byPK[pk].Tags, tag)
+	}
+	return rows.Err()
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, pk int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO idea_tags (idea_pk, tag) VALUES (?, ?)", pk, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteFilters returns the conditions and arguments of the Since, Until and
+// Tag filters of req.
+func sqliteFilters(req *ideas.GetIdeasRequest) ([]string, []any) {
+	var where []string
+	var args []any
+
+	if !req.Since.IsZero() {
+		where = append(where, "ideas.time >= ?")
+		args = append(args, req.Since.UnixNano())
+	}
+	if !req.Until.IsZero() {
+		where = append(where, "ideas.time < ?")
+		args = append(args, req.Until.UnixNano())
+	}
+	if req.Tag != "" {
+		where = append(where, "ideas.pk IN (SELECT idea_pk FROM idea_tags WHERE tag = ?)")
+		args = append(args, req.Tag)
+	}
+	return where, args
+}
+
+// sqliteWhere joins conditions into a WHERE clause, empty without conditions.
+func sqliteWhere(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// ftsQuery converts query to FTS5 syntax, where every clause is a quoted
+// phrase and all of them must match. Terms only have letters and digits, so
+// they need no escaping.
+func ftsQuery(query *search.Query) string {
+	phrases := make([]string, 0, len(query.Clauses))
+	for _, clause := range query.Clauses {
+		phrases = append(phrases, `"`+strings.Join(clause, " ")+`"`)
+	}
+	return strings.Join(phrases, " ")
+}
+
+// nullTime stores zero times as NULL.
+func nullTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func ideasOf(list []*sqliteIdea) []*ideas.Idea {
+	result := make([]*ideas.Idea, 0, len(list))
+	for _, row := range list {
+		result = append(result, row.idea)
+	}
+	return result
+}
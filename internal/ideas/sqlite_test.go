@@ -0,0 +1,333 @@
+package ideas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+func newTestSQLite(t *testing.T) *IdeasSQLiteClient {
+	t.Helper()
+
+	repo, err := NewIdeasSQLiteClient(config.Ideas{Path: filepath.Join(t.TempDir(), "ideas.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.db.Close() })
+	return repo
+}
+
+// postIdeas posts a published idea with each text, an hour apart and oldest
+// first, and returns them.
+func postIdeas(t *testing.T, repo IdeasRepository, texts ...string) []*ideas.Idea {
+	t.Helper()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var posted []*ideas.Idea
+	for i, text := range texts {
+		response, err := repo.PostIdea(context.Background(), &ideas.PostIdeaRequest{
+			Idea:      text,
+			Status:    ideas.StatusPublished,
+			PublishAt: start.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		posted = append(posted, response.Idea)
+	}
+	return posted
+}
+
+func ideaTexts(list []*ideas.Idea) []string {
+	texts := []string{}
+	for _, idea := range list {
+		texts = append(texts, idea.Idea)
+	}
+	return texts
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ideas.db")
+
+	// A database that exists already, with user_version 0.
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE unrelated (x INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	repo, err := NewIdeasSQLiteClient(config.Ideas{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	postIdeas(t, repo, "Kept across restarts.")
+	repo.db.Close()
+
+	// Migrations that were applied aren't applied again.
+	repo, err = NewIdeasSQLiteClient(config.Ideas{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.db.Close()
+
+	var version int
+	if err := repo.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(sqliteMigrations))
+	}
+
+	response, err := repo.GetIdeas(context.Background(), &ideas.GetIdeasRequest{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ideaTexts(response.Ideas); !reflect.DeepEqual(got, []string{"Kept across restarts."}) {
+		t.Errorf("GetIdeas() = %q, want the idea posted before restarting", got)
+	}
+
+	// Databases migrated by newer servers are refused.
+	if _, err := repo.db.Exec("PRAGMA user_version = 1000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIdeasSQLiteClient(config.Ideas{Path: path}); err == nil {
+		t.Error("NewIdeasSQLiteClient() succeeded with a newer schema, want an error")
+	}
+}
+
+func TestSQLiteQueryIdeasCursor(t *testing.T) {
+	repo := newTestSQLite(t)
+	postIdeas(t, repo, "one", "two", "three", "four", "five")
+	ctx := context.Background()
+
+	var got []string
+	req := &ideas.GetIdeasRequest{Limit: 2}
+	for pages := 1; ; pages++ {
+		response, err := repo.QueryIdeas(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantTotal := min(pages+4, 6); response.Total != wantTotal {
+			t.Errorf("page %d: total = %d, want %d", pages, response.Total, wantTotal)
+		}
+
+		got = append(got, ideaTexts(response.Ideas)...)
+		if response.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("%d pages, want 3", pages)
+			}
+			break
+		}
+
+		// Ideas posted while paging don't shift the next pages, but count.
+		if pages == 1 {
+			_, err := repo.PostIdea(ctx, &ideas.PostIdeaRequest{Idea: "newer", Status: ideas.StatusPublished})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		req = &ideas.GetIdeasRequest{Limit: 2, Cursor: response.NextCursor}
+	}
+
+	want := []string{"five", "four", "three", "two", "one"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %q, want %q", got, want)
+	}
+
+	if _, err := repo.QueryIdeas(ctx, &ideas.GetIdeasRequest{Limit: 2, Cursor: "garbage"}); err == nil {
+		t.Error("QueryIdeas() succeeded with an invalid cursor, want an error")
+	}
+}
+
+func TestSQLiteSearchIdeas(t *testing.T) {
+	repo := newTestSQLite(t)
+	postIdeas(t, repo,
+		"Coffee at the café downtown",
+		"A generic type in Go",
+		"Types are generic in Go, they say",
+		"Go to the café for generic coffee",
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "accent-insensitive",
+			query: "cafe",
+			want:  []string{"Go to the café for generic coffee", "Coffee at the café downtown"},
+		},
+		{
+			name:  "phrase",
+			query: `"generic type"`,
+			want:  []string{"A generic type in Go"},
+		},
+		{
+			name:  "all words",
+			query: "generic go",
+			want:  []string{"Go to the café for generic coffee", "Types are generic in Go, they say", "A generic type in Go"},
+		},
+		{
+			name:  "no match",
+			query: "tea",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := repo.SearchIdeas(context.Background(), &ideas.GetIdeasRequest{Query: tt.query, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, match := range response.Ideas {
+				got = append(got, match.Idea.Idea)
+			}
+			// Only which ideas match is checked, their ranking is BM25's.
+			if !sameElements(got, tt.want) {
+				t.Errorf("SearchIdeas(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+			if response.Total != len(tt.want) {
+				t.Errorf("total = %d, want %d", response.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+// sameElements tells whether a and b have the same strings, in any order.
+func sameElements(a, b []string) bool {
+	counts := make(map[string]int)
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+	}
+	for _, count := range counts {
+		if count != 0 {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestSQLiteTags(t *testing.T) {
+	repo := newTestSQLite(t)
+	posted := postIdeas(t, repo, "About #go and #sqlite", "Only #go", "No tags")
+	ctx := context.Background()
+
+	if !reflect.DeepEqual(posted[0].Tags, []string{"go", "sqlite"}) {
+		t.Fatalf("tags = %q, want the hashtags in order", posted[0].Tags)
+	}
+	postedTags := make(map[string][]string)
+	for _, idea := range posted {
+		postedTags[idea.ID] = idea.Tags
+	}
+
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{tag: "go", want: []string{"Only #go", "About #go and #sqlite"}},
+		{tag: "sqlite", want: []string{"About #go and #sqlite"}},
+		{tag: "rust", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			response, err := repo.QueryIdeas(ctx, &ideas.GetIdeasRequest{Tag: tt.tag, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ideaTexts(response.Ideas); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryIdeas(tag=%s) = %q, want %q", tt.tag, got, tt.want)
+			}
+			if response.Total != len(tt.want) {
+				t.Errorf("total = %d, want %d", response.Total, len(tt.want))
+			}
+
+			// Every idea of the page comes with all of its tags.
+			for _, idea := range response.Ideas {
+				if want := postedTags[idea.ID]; !reflect.DeepEqual(idea.Tags, want) {
+					t.Errorf("tags of %q = %q, want %q", idea.Idea, idea.Tags, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteDeleteAndRestore(t *testing.T) {
+	repo := newTestSQLite(t)
+	posted := postIdeas(t, repo, "Kept", "Deleted #gone")
+	deleted := posted[1]
+	ctx := context.Background()
+
+	published := func() []string {
+		t.Helper()
+
+		response, err := repo.QueryIdeas(ctx, &ideas.GetIdeasRequest{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ideaTexts(response.Ideas)
+	}
+
+	_, err := repo.UpdateIdea(ctx, deleted.ID, func(idea *ideas.Idea) error {
+		idea.DeletedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := published(); !reflect.DeepEqual(got, []string{"Kept"}) {
+		t.Errorf("published ideas = %q, want the deleted one hidden", got)
+	}
+
+	// The repository still has it, for the trash.
+	response, err := repo.GetIdeas(ctx, &ideas.GetIdeasRequest{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Ideas) != 2 || response.Ideas[0].DeletedAt.IsZero() {
+		t.Errorf("GetIdeas() = %+v, want both ideas, the newest deleted", response.Ideas)
+	}
+
+	restored, err := repo.UpdateIdea(ctx, deleted.ID, func(idea *ideas.Idea) error {
+		idea.DeletedAt = time.Time{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Tags, []string{"gone"}) {
+		t.Errorf("restored tags = %q, want them kept", restored.Tags)
+	}
+	if got := published(); !reflect.DeepEqual(got, []string{"Deleted #gone", "Kept"}) {
+		t.Errorf("published ideas = %q, want the restored one back", got)
+	}
+
+	// Purging deletes it for good.
+	if err := repo.DeleteIdea(ctx, deleted.ID, func(*ideas.Idea) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteIdea(ctx, deleted.ID, func(*ideas.Idea) error { return nil }); !errors.Is(err, ErrIdeaNotFound) {
+		t.Errorf("DeleteIdea() of a purged idea = %v, want %v", err, ErrIdeaNotFound)
+	}
+	if got := published(); !reflect.DeepEqual(got, []string{"Kept"}) {
+		t.Errorf("published ideas = %q, want the purged one gone", got)
+	}
+}
@@ -0,0 +1,120 @@
+package ideas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jaehnri/website-backend/internal/httpcache"
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+const (
+	TagsPath = "/ideas/tags"
+
+	// Feeds of the ideas with a tag. Their routes use the same handlers as
+	// the feeds of all ideas.
+	TagRSSPath      = "/ideas/tags/{tag}/feed.xml"
+	TagAtomPath     = "/ideas/tags/{tag}/atom.xml"
+	TagJSONFeedPath = "/ideas/tags/{tag}/feed.json"
+
+	// MaxTagLength is the maximum length of tags, in bytes.
+	MaxTagLength = 50
+)
+
+var (
+	// tagPattern matches valid tags, after normalization. Tags of digits only
+	// aren't valid, so "#1" isn't a hashtag.
+	tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]*\p{L}[\p{L}\p{N}_-]*$`)
+
+	// hashtagPattern matches #hashtags that start a word, so URL fragments and
+	// HTML entities aren't hashtags.
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_-]+)`)
+)
+
+// HandleTags receives an HTTP request and returns every tag and how many ideas
+// have it in GetTagsResponse format, most used first.
+func (s *IdeasClient) HandleTags(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.getIdeas(r.Context())
+	if err != nil {
+		http.Error(w, "failed to fetch my ideas", http.StatusInternalServerError)
+		return
+	}
+
+	response := &ideas.GetTagsResponse{
+		Tags: make([]*ideas.TagCount, 0, len(snapshot.byTag)),
+	}
+	for tag, tagIdeas := range snapshot.byTag {
+		response.Tags = append(response.Tags, &ideas.TagCount{Tag: tag, Count: len(tagIdeas)})
+	}
+	sort.Slice(response.Tags, func(a, b int) bool {
+		if response.Tags[a].Count != response.Tags[b].Count {
+			return response.Tags[a].Count > response.Tags[b].Count
+		}
+		return response.Tags[a].Tag < response.Tags[b].Tag
+	})
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+
+	httpcache.ServeJSONModified(w, r, body, s.cacheControl, snapshot.lastModified)
+}
+
+// normalizeTag lowercases tag and strips its leading #, if any. It returns
+// false if the tag isn't valid.
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	return tag, len(tag) <= MaxTagLength && tagPattern.MatchString(tag)
+}
+
+// normalizeTags normalizes the tags of a PostIdeaRequest.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalizedTag, ok := normalizeTag(tag)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q: tags must have up to %d letters, digits, - or _, and at least one letter", tag, MaxTagLength)
+		}
+		normalized = append(normalized, normalizedTag)
+	}
+	return normalized, nil
+}
+
+// ideaTags returns the explicit tags followed by the hashtags of text, without
+// duplicates. Invalid hashtags are ignored.
+func ideaTags(explicit []string, text string) []string {
+	seen := make(map[string]bool)
+
+	var tags []string
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, tag := range explicit {
+		add(tag)
+	}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		if tag, ok := normalizeTag(match[1]); ok {
+			add(tag)
+		}
+	}
+	return tags
+}
+
+// fillTags sets the tags of ideas stored before tags existed from their hashtags.
+func fillTags(allIdeas []*ideas.Idea) {
+	for _, idea := range allIdeas {
+		if idea.Tags == nil {
+			idea.Tags = ideaTags(nil, idea.Idea)
+		}
+	}
+}
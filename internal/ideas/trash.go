@@ -0,0 +1,128 @@
+package ideas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+const (
+	IdeaPath    = "/ideas/{id}"
+	TrashPath   = "/ideas/trash"
+	RestorePath = "/ideas/trash/{id}/restore"
+)
+
+// HandleDeleteIdea receives an HTTP request and moves an idea to the trash.
+// It's hidden right away, but only purged after the trash retention.
+func (s *IdeasClient) HandleDeleteIdea(w http.ResponseWriter, r *http.Request) {
+	s.updateIdea(w, r, EventDeleted, func(idea *ideas.Idea) bool {
+		return idea.DeletedAt.IsZero()
+	}, func(idea *ideas.Idea) {
+		idea.DeletedAt = time.Now()
+	})
+}
+
+// HandleRestoreIdea receives an HTTP request and restores an idea from the trash.
+func (s *IdeasClient) HandleRestoreIdea(w http.ResponseWriter, r *http.Request) {
+	s.updateIdea(w, r, EventRestored, func(idea *ideas.Idea) bool {
+		return !idea.DeletedAt.IsZero()
+	}, func(idea *ideas.Idea) {
+		idea.DeletedAt = time.Time{}
+	})
+}
+
+// HandleGetTrash receives an HTTP request and returns a page of deleted ideas
+// in GetIdeasResponse format.
+func (s *IdeasClient) HandleGetTrash(w http.ResponseWriter, r *http.Request) {
+	s.servePrivateIdeas(w, r, func(snapshot *ideasSnapshot) []*ideas.Idea {
+		return snapshot.trash
+	})
+}
+
+// updateIdea applies update to the stored idea of the request, if it can be
+// updated, and responds with it. Whether it can be updated is checked on the
+// stored idea, so concurrent updates of other replicas aren't overwritten.
+func (s *IdeasClient) updateIdea(w http.ResponseWriter, r *http.Request, eventType EventType, canUpdate func(*ideas.Idea) bool, update func(*ideas.Idea)) {
+	id := r.PathValue("id")
+	updated, err := s.ideasRepo.UpdateIdea(r.Context(), id, func(stored *ideas.Idea) error {
+		if !canUpdate(stored) {
+			return ErrIdeaConflict
+		}
+		update(stored)
+		return nil
+	})
+	if errors.Is(err, ErrIdeaNotFound) || errors.Is(err, ErrIdeaConflict) {
+		http.Error(w, "idea not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to update idea", "idea_id", id, "err", err)
+		http.Error(w, "failed to update idea", http.StatusInternalServerError)
+		return
+	}
+
+	s.applyIdea(updated)
+	s.emit(r.Context(), &Event{Type: eventType, Idea: updated})
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(updated)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RunPurger purges ideas that have been in the trash for longer than the
+// retention, until ctx is done.
+func (s *IdeasClient) RunPurger(ctx context.Context) {
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeExpired(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired deletes for good the ideas deleted before now minus the retention.
+func (s *IdeasClient) purgeExpired(ctx context.Context, now time.Time) {
+	snapshot, err := s.getIdeas(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch deleted ideas", "err", err)
+		return
+	}
+
+	for _, idea := range snapshot.trash {
+		if now.Sub(idea.DeletedAt) < s.trashRetention {
+			continue
+		}
+
+		// The idea may have been restored since the snapshot was taken.
+		err := s.ideasRepo.DeleteIdea(ctx, idea.ID, func(stored *ideas.Idea) error {
+			if stored.DeletedAt.IsZero() || now.Sub(stored.DeletedAt) < s.trashRetention {
+				return ErrIdeaConflict
+			}
+			return nil
+		})
+		if errors.Is(err, ErrIdeaConflict) {
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrIdeaNotFound) {
+			slog.ErrorContext(ctx, "failed to purge idea", "idea_id", idea.ID, "err", err)
+			continue
+		}
+
+		s.forgetIdea(idea.ID)
+		s.emit(ctx, &Event{Type: EventPurged, Idea: idea})
+	}
+}
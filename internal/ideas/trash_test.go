@@ -0,0 +1,142 @@
+package ideas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/ideas"
+)
+
+// deleteIdea moves the idea with id to the trash of repo at deletedAt.
+func deleteIdea(t *testing.T, repo IdeasRepository, id string, deletedAt time.Time) {
+	t.Helper()
+
+	_, err := repo.UpdateIdea(context.Background(), id, func(idea *ideas.Idea) error {
+		idea.DeletedAt = deletedAt
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// trashTexts returns the texts of the ideas in the trash of the snapshot of
+// client.
+func trashTexts(t *testing.T, client *IdeasClient) []string {
+	t.Helper()
+
+	snapshot, err := client.getIdeas(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ideaTexts(snapshot.trash)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	repo := newTestSQLite(t)
+	posted := postIdeas(t, repo, "kept", "expired", "recent")
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	deleteIdea(t, repo, posted[1].ID, now.Add(-31*24*time.Hour))
+	deleteIdea(t, repo, posted[2].ID, now.Add(-24*time.Hour))
+
+	client, events := newTestClient(repo)
+	client.purgeExpired(context.Background(), now)
+
+	// Ideas are purged once they've been in the trash for the retention.
+	if got, want := trashTexts(t, client), []string{"recent"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trash = %q, want %q", got, want)
+	}
+	if want := []string{"idea.purged expired"}; !reflect.DeepEqual(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+
+	response, err := repo.GetIdeas(context.Background(), &ideas.GetIdeasRequest{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ideaTexts(response.Ideas), []string{"recent", "kept"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored ideas = %q, want %q", got, want)
+	}
+
+	client.purgeExpired(context.Background(), now.Add(30*24*time.Hour))
+	if got := trashTexts(t, client); len(got) != 0 {
+		t.Errorf("trash a month later = %q, want it empty", got)
+	}
+}
+
+func TestPurgeExpiredRestoredIdea(t *testing.T) {
+	repo := newTestSQLite(t)
+	posted := postIdeas(t, repo, "restored")
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	deleteIdea(t, repo, posted[0].ID, now.Add(-31*24*time.Hour))
+
+	client, events := newTestClient(repo)
+	trashTexts(t, client)
+
+	// It's restored, maybe by another replica, after the snapshot was taken.
+	deleteIdea(t, repo, posted[0].ID, time.Time{})
+
+	client.purgeExpired(context.Background(), now)
+	if len(*events) != 0 {
+		t.Errorf("events = %q, want none", *events)
+	}
+	if _, err := repo.UpdateIdea(context.Background(), posted[0].ID, func(*ideas.Idea) error { return nil }); err != nil {
+		t.Errorf("restored idea was purged: %v", err)
+	}
+}
+
+func TestDeleteAndRestoreIdea(t *testing.T) {
+	repo := newTestSQLite(t)
+	posted := postIdeas(t, repo, "kept", "deleted")
+	id := posted[1].ID
+
+	client, events := newTestClient(repo)
+
+	serve := func(handler http.HandlerFunc, method string, url string) int {
+		r := httptest.NewRequest(method, url, nil)
+		r.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	if code := serve(client.HandleDeleteIdea, "DELETE", "/ideas/"+id); code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want %d", code, http.StatusOK)
+	}
+	published, _ := snapshotTexts(t, client)
+	if want := []string{"kept"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published ideas = %q, want %q", published, want)
+	}
+	if got, want := trashTexts(t, client), []string{"deleted"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trash = %q, want %q", got, want)
+	}
+
+	// Ideas can only be deleted once.
+	if code := serve(client.HandleDeleteIdea, "DELETE", "/ideas/"+id); code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if code := serve(client.HandleRestoreIdea, "POST", "/ideas/trash/"+id+"/restore"); code != http.StatusOK {
+		t.Fatalf("restore status = %d, want %d", code, http.StatusOK)
+	}
+	published, _ = snapshotTexts(t, client)
+	if want := []string{"deleted", "kept"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published ideas after restoring = %q, want %q", published, want)
+	}
+	if got := trashTexts(t, client); len(got) != 0 {
+		t.Errorf("trash after restoring = %q, want it empty", got)
+	}
+
+	// Ideas that aren't in the trash can't be restored.
+	if code := serve(client.HandleRestoreIdea, "POST", "/ideas/trash/"+id+"/restore"); code != http.StatusNotFound {
+		t.Errorf("second restore status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if want := []string{"idea.deleted deleted", "idea.restored deleted"}; !reflect.DeepEqual(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+}
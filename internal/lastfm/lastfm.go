@@ -0,0 +1,123 @@
+package lastfm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/metrics"
+	"github.com/jaehnri/website-backend/internal/tracing"
+	lastfmapi "github.com/jaehnri/website-backend/pkg/lastfm"
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+)
+
+var ErrNoRecentTracks = errors.New("last.fm user has no recent tracks")
+
+// LastFMClient fetches the current playing song from a Last.fm profile.
+type LastFMClient struct {
+	httpClient http.Client
+
+	// baseURL is configurable so the client can be pointed at a local fake.
+	baseURL string
+	apiKey  string
+	user    string
+}
+
+func NewLastFMClient(cfg config.LastFM) *LastFMClient {
+	return &LastFMClient{
+		httpClient: http.Client{Transport: tracing.NewTransport(metrics.NewTransport("lastfm", nil), nil)},
+		baseURL:    cfg.BaseURL,
+		apiKey:     cfg.APIKey.Value(),
+		user:       cfg.User,
+	}
+}
+
+func (l *LastFMClient) Name() string {
+	return "lastfm"
+}
+
+// GetNowPlaying returns the song currently being scrobbled or, if there's none,
+// the last scrobbled one.
+func (l *LastFMClient) GetNowPlaying(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	req, err := l.buildRecentTracksRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("last.fm recent tracks request failed with status %d", resp.StatusCode)
+	}
+
+	recentTracksResponse, err := parseRecentTracksResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertRecentTracksResponse(recentTracksResponse)
+}
+
+func (l *LastFMClient) buildRecentTracksRequest(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", l.baseURL, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create last.fm recent tracks request", "err", err)
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("method", "user.getrecenttracks")
+	q.Add("user", l.user)
+	q.Add("api_key", l.apiKey)
+	q.Add("format", "json")
+	q.Add("limit", "1")
+	req.URL.RawQuery = q.Encode()
+
+	return req, nil
+}
+
+func parseRecentTracksResponse(resp *http.Response) (*lastfmapi.RecentTracksResponse, error) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.ErrorContext(resp.Request.Context(), "failed to read last.fm recent tracks response body", "err", err)
+		return nil, err
+	}
+
+	var recentTracksResponse lastfmapi.RecentTracksResponse
+	err = json.Unmarshal(bodyBytes, &recentTracksResponse)
+	if err != nil {
+		slog.ErrorContext(resp.Request.Context(), "failed to unmarshal last.fm recent tracks JSON", "err", err)
+		return nil, err
+	}
+
+	return &recentTracksResponse, nil
+}
+
+func convertRecentTracksResponse(apiResponse *lastfmapi.RecentTracksResponse) (*nowplaying.CurrentSong, error) {
+	tracks := apiResponse.RecentTracks.Tracks
+	if len(tracks) == 0 {
+		return nil, ErrNoRecentTracks
+	}
+
+	// The track being scrobbled right now, if any, always comes first.
+	track := tracks[0]
+	return &nowplaying.CurrentSong{
+		IsPlaying: track.Attr != nil && track.Attr.NowPlaying == "true",
+
+		// Last.fm doesn't know about playback progress nor duration.
+		ProgressMs: 0,
+		DurationMs: 0,
+
+		Song:   track.Name,
+		Artist: track.Artist.Name,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package lastfm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+)
+
+func TestGetNowPlaying(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   *nowplaying.CurrentSong
+		err    error
+	}{
+		{
+			name:   "now playing",
+			status: http.StatusOK,
+			body: `{"recenttracks": {"track": [
+				{"name": "Song", "artist": {"#text": "Artist"}, "@attr": {"nowplaying": "true"}},
+				{"name": "Older", "artist": {"#text": "Someone"}}
+			]}}`,
+			want: &nowplaying.CurrentSong{IsPlaying: true, Song: "Song", Artist: "Artist"},
+		},
+		{
+			name:   "last scrobbled",
+			status: http.StatusOK,
+			body:   `{"recenttracks": {"track": [{"name": "Song", "artist": {"#text": "Artist"}}]}}`,
+			want:   &nowplaying.CurrentSong{Song: "Song", Artist: "Artist"},
+		},
+		{
+			name:   "single track object",
+			status: http.StatusOK,
+			body:   `{"recenttracks": {"track": {"name": "Song", "artist": {"#text": "Artist"}}}}`,
+			want:   &nowplaying.CurrentSong{Song: "Song", Artist: "Artist"},
+		},
+		{
+			name:   "no tracks",
+			status: http.StatusOK,
+			body:   `{"recenttracks": {"track": []}}`,
+			err:    ErrNoRecentTracks,
+		},
+		{
+			name:   "upstream error",
+			status: http.StatusInternalServerError,
+			body:   `{"error": 8, "message": "Operation failed"}`,
+			err:    errAny,
+		},
+		{
+			name:   "invalid JSON",
+			status: http.StatusOK,
+			body:   `{"recenttracks":`,
+			err:    errAny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query map[string][]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewLastFMClient(config.LastFM{BaseURL: server.URL, APIKey: "key", User: "me"})
+			got, err := client.GetNowPlaying(context.Background())
+
+			switch {
+			case tt.err == errAny && err == nil:
+				t.Fatal("GetNowPlaying() succeeded, want an error")
+			case tt.err != errAny && !errors.Is(err, tt.err):
+				t.Fatalf("GetNowPlaying() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNowPlaying() = %+v, want %+v", got, tt.want)
+			}
+
+			wantQuery := map[string][]string{
+				"method":  {"user.getrecenttracks"},
+				"user":    {"me"},
+				"api_key": {"key"},
+				"format":  {"json"},
+				"limit":   {"1"},
+			}
+			if !reflect.DeepEqual(query, wantQuery) {
+				t.Errorf("query = %v, want %v", query, wantQuery)
+			}
+		})
+	}
+}
+
+// errAny stands for any error in test cases.
+var errAny = errors.New("any error")
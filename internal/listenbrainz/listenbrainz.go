@@ -0,0 +1,149 @@
+package listenbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/metrics"
+	"github.com/jaehnri/website-backend/internal/tracing"
+	listenbrainzapi "github.com/jaehnri/website-backend/pkg/listenbrainz"
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+)
+
+var ErrNoListens = errors.New("listenbrainz user has no listens")
+
+// ListenBrainzClient fetches the current playing song from a ListenBrainz profile.
+type ListenBrainzClient struct {
+	httpClient http.Client
+
+	// baseURL is configurable so the client can be pointed at a local fake.
+	baseURL string
+	user    string
+
+	// token is optional. Public profiles can be read anonymously, but
+	// authenticated requests get higher rate limits.
+	token string
+}
+
+func NewListenBrainzClient(cfg config.ListenBrainz) *ListenBrainzClient {
+	return &ListenBrainzClient{
+		httpClient: http.Client{Transport: tracing.NewTransport(metrics.NewTransport("listenbrainz", nil), nil)},
+		baseURL:    cfg.BaseURL,
+		user:       cfg.User,
+		token:      cfg.Token.Value(),
+	}
+}
+
+func (l *ListenBrainzClient) Name() string {
+	return "listenbrainz"
+}
+
+// GetNowPlaying returns the song currently being played or, if there's none,
+// the last listened one.
+func (l *ListenBrainzClient) GetNowPlaying(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	playingNow, err := l.getListens(ctx, "playing-now", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Playing now endpoint returns an empty list when nothing is on.
+	// In this case, we can get the last listen instead.
+	if len(playingNow.Payload.Listens) > 0 {
+		return convertListen(&playingNow.Payload.Listens[0], true), nil
+	}
+
+	lastListens, err := l.getListens(ctx, "listens", url.Values{"count": {"1"}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lastListens.Payload.Listens) == 0 {
+		return nil, ErrNoListens
+	}
+
+	return convertListen(&lastListens.Payload.Listens[0], false), nil
+}
+
+func (l *ListenBrainzClient) getListens(ctx context.Context, endpoint string, query url.Values) (*listenbrainzapi.ListensResponse, error) {
+	req, err := l.buildListensRequest(ctx, endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listenbrainz %s request failed with status %d", endpoint, resp.StatusCode)
+	}
+
+	return parseListensResponse(resp)
+}
+
+func (l *ListenBrainzClient) buildListensRequest(ctx context.Context, endpoint string, query url.Values) (*http.Request, error) {
+	endpointURL, err := url.JoinPath(l.baseURL, "1", "user", l.user, endpoint)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build listenbrainz URL", "err", err)
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpointURL, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create listenbrainz request", "err", err)
+		return nil, err
+	}
+	req.URL.RawQuery = query.Encode()
+
+	if l.token != "" {
+		req.Header.Set("Authorization", "Token "+l.token)
+	}
+	return req, nil
+}
+
+func parseListensResponse(resp *http.Response) (*listenbrainzapi.ListensResponse, error) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.ErrorContext(resp.Request.Context(), "failed to read listenbrainz response body", "err", err)
+		return nil, err
+	}
+
+	var listensResponse listenbrainzapi.ListensResponse
+	err = json.Unmarshal(bodyBytes, &listensResponse)
+	if err != nil {
+		slog.ErrorContext(resp.Request.Context(), "failed to unmarshal listenbrainz listens JSON", "err", err)
+		return nil, err
+	}
+
+	return &listensResponse, nil
+}
+
+func convertListen(listen *listenbrainzapi.Listen, isPlaying bool) *nowplaying.CurrentSong {
+	return &nowplaying.CurrentSong{
+		IsPlaying: isPlaying,
+
+		// ListenBrainz doesn't know about playback progress. Like Spotify's
+		// last played song, a finished song is reported as fully played.
+		ProgressMs: progress(listen, isPlaying),
+		DurationMs: listen.TrackMetadata.AdditionalInfo.DurationMs,
+
+		Song:   listen.TrackMetadata.TrackName,
+		Artist: listen.TrackMetadata.ArtistName,
+	}
+}
+
+func progress(listen *listenbrainzapi.Listen, isPlaying bool) int {
+	if isPlaying {
+		return 0
+	}
+	return listen.TrackMetadata.AdditionalInfo.DurationMs
+}
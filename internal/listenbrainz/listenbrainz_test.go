@@ -0,0 +1,97 @@
+package listenbrainz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+)
+
+func TestGetNowPlaying(t *testing.T) {
+	const (
+		playing = `{"payload": {"count": 1, "playing_now": true, "listens": [{"playing_now": true, "track_metadata": {
+			"artist_name": "Artist", "track_name": "Song", "additional_info": {"duration_ms": 180000}}}]}}`
+		listened = `{"payload": {"count": 1, "listens": [{"listened_at": 1700000000, "track_metadata": {
+			"artist_name": "Artist", "track_name": "Older", "additional_info": {"duration_ms": 200000}}}]}}`
+		empty = `{"payload": {"count": 0, "listens": []}}`
+	)
+
+	tests := []struct {
+		name  string
+		token string
+
+		// responses by path.
+		responses map[string]string
+
+		want *nowplaying.CurrentSong
+		err  error
+	}{
+		{
+			name:      "playing now",
+			responses: map[string]string{"/1/user/me/playing-now": playing},
+			want:      &nowplaying.CurrentSong{IsPlaying: true, DurationMs: 180000, Song: "Song", Artist: "Artist"},
+		},
+		{
+			name:      "last listen",
+			token:     "secret",
+			responses: map[string]string{"/1/user/me/playing-now": empty, "/1/user/me/listens": listened},
+			want:      &nowplaying.CurrentSong{ProgressMs: 200000, DurationMs: 200000, Song: "Older", Artist: "Artist"},
+		},
+		{
+			name:      "no listens",
+			responses: map[string]string{"/1/user/me/playing-now": empty, "/1/user/me/listens": empty},
+			err:       ErrNoListens,
+		},
+		{
+			name:      "upstream error",
+			responses: map[string]string{},
+			err:       errAny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wantAuthorization := ""
+				if tt.token != "" {
+					wantAuthorization = "Token " + tt.token
+				}
+				if got := r.Header.Get("Authorization"); got != wantAuthorization {
+					t.Errorf("Authorization = %q, want %q", got, wantAuthorization)
+				}
+				if r.URL.Path == "/1/user/me/listens" && r.URL.Query().Get("count") != "1" {
+					t.Errorf("count = %q, want 1", r.URL.Query().Get("count"))
+				}
+
+				body, exists := tt.responses[r.URL.Path]
+				if !exists {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			client := NewListenBrainzClient(config.ListenBrainz{BaseURL: server.URL, User: "me", Token: config.Secret(tt.token)})
+			got, err := client.GetNowPlaying(context.Background())
+
+			switch {
+			case tt.err == errAny && err == nil:
+				t.Fatal("GetNowPlaying() succeeded, want an error")
+			case tt.err != errAny && !errors.Is(err, tt.err):
+				t.Fatalf("GetNowPlaying() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNowPlaying() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// errAny stands for any error in test cases.
+var errAny = errors.New("any error")
@@ -0,0 +1,155 @@
+package listening
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/storage"
+	"github.com/jaehnri/website-backend/pkg/listening"
+)
+
+// MonthLayout is how months are formatted in URLs and object names.
+const MonthLayout = "2006-01"
+
+// TopItemsProvider knows my most listened songs and artists in the last 4 weeks.
+type TopItemsProvider interface {
+	GetTopTracks(ctx context.Context) ([]*listening.TopTrack, error)
+	GetTopArtists(ctx context.Context) ([]*listening.TopArtist, error)
+}
+
+// ListeningClient periodically snapshots my top items and serves them, so the
+// site doesn't depend on Spotify's rolling windows.
+type ListeningClient struct {
+	store            storage.ObjectStore
+	topItemsProvider TopItemsProvider
+
+	snapshotInterval time.Duration
+
+	// running tells whether the snapshot job is running.
+	running atomic.Bool
+}
+
+func NewListeningClient(cfg config.Listening, topItemsProvider TopItemsProvider) (*ListeningClient, error) {
+	store, err := newObjectStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListeningClient{
+		store:            store,
+		topItemsProvider: topItemsProvider,
+		snapshotInterval: cfg.SnapshotInterval,
+	}, nil
+}
+
+func newObjectStore(cfg config.Listening) (storage.ObjectStore, error) {
+	if cfg.Bucket != "" {
+		return storage.NewGCSStore(cfg.Bucket, "")
+	}
+	return storage.NewFileStore(cfg.Dir)
+}
+
+// HandleMonthly receives an HTTP request and returns the snapshot of a given
+// month in MonthlySnapshot format.
+func (l *ListeningClient) HandleMonthly(w http.ResponseWriter, r *http.Request) {
+	month, err := time.Parse(MonthLayout, r.PathValue("month"))
+	if err != nil {
+		http.Error(w, "month must be formatted as yyyy-mm", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := l.getSnapshot(r.Context(), month)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		http.Error(w, "no snapshot for this month", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(snapshot)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleWrapped receives an HTTP request and returns the aggregation of all
+// snapshots of a given year in WrappedResponse format.
+func (l *ListeningClient) HandleWrapped(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(r.PathValue("year"))
+	if err != nil {
+		http.Error(w, "invalid year", http.StatusBadRequest)
+		return
+	}
+
+	snapshots, err := l.getYearSnapshots(r.Context(), year)
+	if err != nil {
+		http.Error(w, "failed to fetch snapshots", http.StatusInternalServerError)
+		return
+	}
+
+	if len(snapshots) == 0 {
+		http.Error(w, "no snapshots for this year", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(wrap(year, snapshots))
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (l *ListeningClient) getSnapshot(ctx context.Context, month time.Time) (*listening.MonthlySnapshot, error) {
+	data, err := l.store.Read(ctx, snapshotName(month))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot listening.MonthlySnapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unmarshal snapshot", "snapshot", snapshotName(month), "err", err)
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+func (l *ListeningClient) getYearSnapshots(ctx context.Context, year int) ([]*listening.MonthlySnapshot, error) {
+	names, err := l.store.List(ctx, snapshotPrefix+strconv.Itoa(year)+"-")
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list snapshots", "err", err)
+		return nil, err
+	}
+
+	var snapshots []*listening.MonthlySnapshot
+	for _, name := range names {
+		month, err := parseSnapshotName(name)
+		if err != nil {
+			slog.WarnContext(ctx, "ignoring unexpected object", "object", name, "err", err)
+			continue
+		}
+
+		snapshot, err := l.getSnapshot(ctx, month)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
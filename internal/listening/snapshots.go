@@ -0,0 +1,120 @@
+package listening
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/storage"
+	"github.com/jaehnri/website-backend/pkg/listening"
+)
+
+const snapshotPrefix = "snapshots/"
+
+// snapshotWindow is how long after a month is over its snapshot can still be
+// taken. Spotify's short term window covers approximately the last 4 weeks,
+// so later on it's mostly made of the next month, and the snapshot is skipped.
+const snapshotWindow = 3 * 24 * time.Hour
+
+// RunSnapshots takes the snapshot of the previous month if it's missing and
+// the month ended less than snapshotWindow ago, then keeps checking every
+// snapshot interval until ctx is cancelled.
+func (l *ListeningClient) RunSnapshots(ctx context.Context) {
+	l.running.Store(true)
+	defer l.running.Store(false)
+
+	ticker := time.NewTicker(l.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		month, ok := snapshotMonth(time.Now())
+		if !ok {
+			slog.DebugContext(ctx, "skipping listening snapshot, the previous month ended too long ago", "month", month.Format(MonthLayout))
+		} else if err := l.takeSnapshot(ctx, month); err != nil {
+			slog.ErrorContext(ctx, "failed to take listening snapshot", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckSnapshots tells whether the snapshot job is running.
+func (l *ListeningClient) CheckSnapshots(_ context.Context) error {
+	if !l.running.Load() {
+		return errors.New("snapshot job is not running")
+	}
+	return nil
+}
+
+func (l *ListeningClient) takeSnapshot(ctx context.Context, month time.Time) error {
+	name := snapshotName(month)
+
+	// Snapshots are immutable, there's no need to query Spotify again.
+	_, err := l.store.Read(ctx, name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrObjectNotExist) {
+		return err
+	}
+
+	tracks, err := l.topItemsProvider.GetTopTracks(ctx)
+	if err != nil {
+		return err
+	}
+
+	artists, err := l.topItemsProvider.GetTopArtists(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&listening.MonthlySnapshot{
+		Month:   month.Format(MonthLayout),
+		TakenAt: time.Now(),
+		Tracks:  tracks,
+		Artists: artists,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Another replica might have taken the same snapshot in the meantime.
+	err = l.store.Create(ctx, name, data)
+	if errors.Is(err, storage.ErrObjectExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "took listening snapshot", "snapshot", name)
+	return nil
+}
+
+// snapshotMonth returns the month whose snapshot is due at now, the previous
+// one, and whether it ended less than snapshotWindow ago.
+func snapshotMonth(now time.Time) (time.Time, bool) {
+	month := previousMonth(now)
+	return month, now.Sub(month.AddDate(0, 1, 0)) < snapshotWindow
+}
+
+func previousMonth(now time.Time) time.Time {
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return firstOfMonth.AddDate(0, -1, 0)
+}
+
+func snapshotName(month time.Time) string {
+	return snapshotPrefix + month.Format(MonthLayout) + ".json"
+}
+
+func parseSnapshotName(name string) (time.Time, error) {
+	monthStr := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), ".json")
+	return time.Parse(MonthLayout, monthStr)
+}
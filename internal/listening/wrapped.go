@@ -0,0 +1,84 @@
+package listening
+
+import (
+	"sort"
+
+	"github.com/jaehnri/website-backend/pkg/listening"
+)
+
+// WrappedLimit is how many tracks and artists a yearly wrapped has.
+const WrappedLimit = 50
+
+// wrap aggregates monthly snapshots into a yearly ranking. Each appearance in a
+// monthly ranking scores points inversely proportional to its rank, so songs
+// that were on repeat for several months rise to the top.
+func wrap(year int, snapshots []*listening.MonthlySnapshot) *listening.WrappedResponse {
+	var months []string
+	trackScores := make(map[string]*scored[listening.TopTrack])
+	artistScores := make(map[string]*scored[listening.TopArtist])
+
+	for _, snapshot := range snapshots {
+		months = append(months, snapshot.Month)
+
+		for _, track := range snapshot.Tracks {
+			addScore(trackScores, track.Song+"\x00"+track.Artist, *track, track.Rank)
+		}
+		for _, artist := range snapshot.Artists {
+			addScore(artistScores, artist.Name, *artist, artist.Rank)
+		}
+	}
+
+	tracks := rank(trackScores)
+	for i, track := range tracks {
+		track.Rank = i + 1
+	}
+
+	artists := rank(artistScores)
+	for i, artist := range artists {
+		artist.Rank = i + 1
+	}
+
+	return &listening.WrappedResponse{
+		Year:    year,
+		Months:  months,
+		Tracks:  tracks,
+		Artists: artists,
+	}
+}
+
+type scored[T any] struct {
+	item  T
+	score int
+}
+
+func addScore[T any](scores map[string]*scored[T], key string, item T, monthlyRank int) {
+	s, exists := scores[key]
+	if !exists {
+		s = &scored[T]{item: item}
+		scores[key] = s
+	}
+	s.score += WrappedLimit + 1 - monthlyRank
+}
+
+// rank sorts items by score, breaking ties by key so rankings are stable.
+func rank[T any](scores map[string]*scored[T]) []*T {
+	keys := make([]string, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		si, sj := scores[keys[i]].score, scores[keys[j]].score
+		if si != sj {
+			return si > sj
+		}
+		return keys[i] < keys[j]
+	})
+
+	items := make([]*T, 0, min(len(keys), WrappedLimit))
+	for _, key := range keys[:min(len(keys), WrappedLimit)] {
+		item := scores[key].item
+		items = append(items, &item)
+	}
+	return items
+}
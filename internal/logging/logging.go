@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces sensitive values in logs.
+const Redacted = config.Redacted
+
+// sensitiveKeys are substrings of attribute keys whose values are never logged.
+var sensitiveKeys = []string{"token", "secret", "password", "authorization", "api_key", "apikey"}
+
+// sensitiveParams matches credentials embedded in messages, e.g. URLs in
+// errors returned by http.Client.
+var sensitiveParams = regexp.MustCompile(`(?i)((?:api_key|token|access_token|refresh_token|client_secret)=|Bearer |Basic )[^&\s"']+`)
+
+// Setup makes slog's default logger, which the log package also writes to,
+// use the configured format and level.
+func Setup(cfg config.Logging, w io.Writer) {
+	slog.SetDefault(slog.New(NewHandler(cfg, w)))
+}
+
+// NewHandler returns a handler that adds the request ID and trace context of
+// the context to every record and redacts secrets.
+func NewHandler(cfg config.Logging, w io.Writer) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       parseLevel(cfg.Level),
+		ReplaceAttr: redact,
+	}
+
+	if cfg.Format == config.LogFormatText {
+		return &contextHandler{slog.NewTextHandler(w, opts)}
+	}
+	return &contextHandler{slog.NewJSONHandler(w, opts)}
+}
+
+func parseLevel(level string) slog.Level {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(level))
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// redact hides the values of sensitive keys, and credentials embedded in
+// messages and errors.
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, RedactString(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, RedactString(err.Error()))
+		}
+	}
+	return attr
+}
+
+// RedactString hides credentials embedded in s, e.g. "api_key=..." in URLs.
+func RedactString(s string) string {
+	return sensitiveParams.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// contextHandler adds the request ID and the trace context to records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID attaches a request ID to ctx, so it's added to every log line.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the ID attached by WithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
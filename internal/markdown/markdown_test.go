@@ -0,0 +1,94 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "emphasis and code",
+			source: "*em* **strong** `code`",
+			want:   "<p><em>em</em> <strong>strong</strong> <code>code</code></p>\n",
+		},
+		{
+			name:   "hard wraps",
+			source: "line1\nline2",
+			want:   "<p>line1<br>\nline2</p>\n",
+		},
+		{
+			name:   "ordered list with start",
+			source: "3. a\n4. b",
+			want:   "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n",
+		},
+		{
+			name:   "link",
+			source: "[ok](https://example.com)",
+			want:   "<p><a href=\"https://example.com\" rel=\"nofollow noreferrer\">ok</a></p>\n",
+		},
+		{
+			name:   "bare URL",
+			source: "see https://example.com now",
+			want:   "<p>see <a href=\"https://example.com\" rel=\"nofollow noreferrer\">https://example.com</a> now</p>\n",
+		},
+		{
+			name:   "headings stay text",
+			source: "# Heading",
+			want:   "<p># Heading</p>\n",
+		},
+		{
+			name:   "javascript link",
+			source: "[x](javascript:alert(1))",
+			want:   "<p>x</p>\n",
+		},
+		{
+			name:   "mixed case javascript link",
+			source: "[x](JaVaScRiPt:alert(1))",
+			want:   "<p>x</p>\n",
+		},
+		{
+			name:   "entity encoded javascript link",
+			source: "[x](&#106;avascript:alert(1))",
+			want:   "<p>x</p>\n",
+		},
+		{
+			name:   "javascript autolink",
+			source: "<javascript:alert(1)>",
+			want:   "<p>javascript:alert(1)</p>\n",
+		},
+		{
+			name:   "data link",
+			source: "[x](data:text/html;base64,PHNjcmlwdD4=)",
+			want:   "<p>x</p>\n",
+		},
+		{
+			name:   "script",
+			source: "<script>alert(1)</script>",
+			want:   "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name:   "raw HTML with event handlers",
+			source: "hi <b onclick=x>there</b> <img src=x onerror=alert(1)>",
+			want:   "<p>hi &lt;b onclick=x&gt;there&lt;/b&gt; &lt;img src=x onerror=alert(1)&gt;</p>\n",
+		},
+		{
+			name:   "images are dropped",
+			source: "![img](https://example.com/y.png)",
+			want:   "<p></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/api/googleapi"
+)
+
+// Results of GCS operations.
+const (
+	GCSResultOK                 = "ok"
+	GCSResultNotFound           = "not_found"
+	GCSResultPreconditionFailed = "precondition_failed"
+	GCSResultError              = "error"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests served, by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "Requests to upstream APIs, e.g. Spotify, by upstream, endpoint and status. Network errors have status \"error\".",
+	}, []string{"upstream", "endpoint", "status"})
+
+	upstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Latency of requests to upstream APIs, by upstream and endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "endpoint"})
+
+	tokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "spotify_token_refreshes_total",
+		Help: "Spotify access token refreshes, by result.",
+	}, []string{"result"})
+
+	gcsOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gcs_operations_total",
+		Help: "GCS operations, by operation and result.",
+	}, []string{"operation", "result"})
+
+	gcsOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gcs_operation_duration_seconds",
+		Help:    "Latency of GCS operations, by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "HTTP requests rejected by the rate limiter, by route.",
+	}, []string{"route"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "In-memory cache lookups, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+// Handler serves all metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest records a request served by the API. Route is the
+// pattern that matched the request, so paths with IDs don't blow up the
+// number of series.
+func ObserveHTTPRequest(route string, method string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// ObserveRateLimited records a request rejected with 429.
+func ObserveRateLimited(route string) {
+	rateLimited.WithLabelValues(route).Inc()
+}
+
+// ObserveTokenRefresh records a Spotify access token refresh.
+func ObserveTokenRefresh(err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	tokenRefreshes.WithLabelValues(result).Inc()
+}
+
+// ObserveGCS records a GCS operation, e.g. "read" or "write", that started at start.
+func ObserveGCS(operation string, start time.Time, err error) {
+	gcsOperations.WithLabelValues(operation, gcsResult(err)).Inc()
+	gcsOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func gcsResult(err error) string {
+	if err == nil {
+		return GCSResultOK
+	}
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return GCSResultNotFound
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return GCSResultPreconditionFailed
+	}
+	return GCSResultError
+}
+
+// ObserveCacheLookup records a cache hit or miss.
+func ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// Transport is an http.RoundTripper that records requests to an upstream API.
+type Transport struct {
+	upstream string
+	base     http.RoundTripper
+
+	// endpoint names the endpoint of a request. It must keep the number of
+	// names bounded, e.g. by replacing IDs in paths.
+	endpoint func(*http.Request) string
+}
+
+// NewTransport instruments requests to upstream. If endpoint is nil, requests
+// are named after their path.
+func NewTransport(upstream string, endpoint func(*http.Request) string) *Transport {
+	if endpoint == nil {
+		endpoint = func(r *http.Request) string {
+			return r.URL.Path
+		}
+	}
+
+	return &Transport{
+		upstream: upstream,
+		base:     http.DefaultTransport,
+		endpoint: endpoint,
+	}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(r)
+
+	endpoint := t.endpoint(r)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	upstreamRequests.WithLabelValues(t.upstream, endpoint, status).Inc()
+	upstreamRequestDuration.WithLabelValues(t.upstream, endpoint).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPResolver finds the IP of the client that sent a request, honoring
+// X-Forwarded-For only when set by trusted proxies. Otherwise, anyone could
+// pick their own IP.
+type ClientIPResolver struct {
+	trustedProxies []*net.IPNet
+}
+
+// NewClientIPResolver accepts CIDRs, e.g. "10.0.0.0/8", or single IPs.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			return nil, err
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, network)
+	}
+	return resolver, nil
+}
+
+func parseNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("trusted proxy %q must be an IP or a CIDR", s)
+		}
+		bits := 8 * len(ip.To16())
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxy %q must be an IP or a CIDR", s)
+	}
+	return network, nil
+}
+
+// ClientIP returns the IP of the client. X-Forwarded-For is walked from the
+// right, as each trusted proxy appends the address it received the request
+// from; the first untrusted address is the client.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !c.isTrusted(remoteIP) {
+		return remoteIP
+	}
+
+	forwardedFor := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	clientIP := remoteIP
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwardedFor[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+
+		clientIP = ip
+		if !c.isTrusted(ip) {
+			break
+		}
+	}
+	return clientIP
+}
+
+func (c *ClientIPResolver) isTrusted(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range c.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
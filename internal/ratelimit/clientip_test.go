@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:1234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:         "untrusted proxy can't pick the IP",
+			remoteAddr:   "203.0.113.7:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "trusted proxy",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "chain of trusted proxies",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1, 192.0.2.1, 10.9.9.9"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "spoofed entries before the first untrusted hop",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1, 10.9.9.9"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "several headers",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"1.1.1.1", "198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "garbage stops the walk",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1, not-an-ip, 10.9.9.9"},
+			want:         "10.9.9.9",
+		},
+		{
+			name:         "only trusted proxies",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"10.9.9.9"},
+			want:         "10.9.9.9",
+		},
+		{
+			name:         "IPv6",
+			remoteAddr:   "[2001:db8::1]:1234",
+			forwardedFor: []string{"2001:db9::42"},
+			want:         "2001:db9::42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := resolver.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolverRejectsInvalidProxies(t *testing.T) {
+	for _, proxy := range []string{"", "10.0.0.0/33", "proxy.local"} {
+		if _, err := NewClientIPResolver([]string{proxy}); err == nil {
+			t.Errorf("NewClientIPResolver(%q) succeeded, want an error", proxy)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Period, refilling continuously. Clients can burst
+// up to Requests at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses limits formatted as "<requests>/<period>", e.g. "10/1h".
+func ParseLimit(s string) (Limit, error) {
+	requestsStr, periodStr, found := strings.Cut(s, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("limit %q must be formatted as <requests>/<period>, e.g. 10/1h", s)
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must allow a positive number of requests", s)
+	}
+
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must have a positive period, e.g. 1m", s)
+	}
+
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+// ParseRouteLimit parses route limits formatted as "<pattern>=<limit>",
+// e.g. "POST /ideas=10/1h".
+func ParseRouteLimit(s string) (string, Limit, error) {
+	pattern, limitStr, found := strings.Cut(s, "=")
+	if !found || pattern == "" {
+		return "", Limit{}, fmt.Errorf("route limit %q must be formatted as <pattern>=<limit>, e.g. POST /ideas=10/1h", s)
+	}
+
+	limit, err := ParseLimit(limitStr)
+	if err != nil {
+		return "", Limit{}, err
+	}
+	return pattern, limit, nil
+}
+
+// refillInterval is how long it takes to earn a single request back.
+func (l Limit) refillInterval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Policy formats the limit for the RateLimit-Policy header, e.g. "10;w=3600".
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Period.Seconds()))
+}
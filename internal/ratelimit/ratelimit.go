@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+)
+
+// Limiter is a thread-safe token bucket rate limiter with a bucket per route
+// and client. Buckets live in a bounded LRU: once a bucket is idle long
+// enough to be full again, it's evicted, as forgetting it changes nothing.
+type Limiter struct {
+	defaultLimit Limit
+
+	// routeLimits overrides the default limit by route pattern, e.g. "POST /ideas".
+	routeLimits map[string]Limit
+
+	clientIPs *ClientIPResolver
+	apiToken  string
+	maxKeys   int
+
+	// lock protects buckets and lru.
+	lock    sync.Mutex
+	buckets map[string]*list.Element
+
+	// lru has the most recently used buckets at the front.
+	lru *list.List
+}
+
+type bucket struct {
+	key   string
+	limit Limit
+
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Result tells whether a request is allowed and the state of its bucket.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+
+	// Remaining is how many requests can be made right away.
+	Remaining int
+
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+
+	// RetryAfter is how long until the next request is allowed, if it isn't now.
+	RetryAfter time.Duration
+}
+
+// NewLimiter expects a validated config, see config.RateLimit. Requests with
+// apiToken are limited by token instead of by IP.
+func NewLimiter(cfg config.RateLimit, apiToken string) (*Limiter, error) {
+	defaultLimit, err := ParseLimit(cfg.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	routeLimits := make(map[string]Limit)
+	for _, routeLimit := range cfg.Routes {
+		pattern, limit, err := ParseRouteLimit(routeLimit)
+		if err != nil {
+			return nil, err
+		}
+		routeLimits[pattern] = limit
+	}
+
+	clientIPs, err := NewClientIPResolver(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Limiter{
+		defaultLimit: defaultLimit,
+		routeLimits:  routeLimits,
+		clientIPs:    clientIPs,
+		apiToken:     apiToken,
+		maxKeys:      cfg.MaxKeys,
+		buckets:      make(map[string]*list.Element),
+		lru:          list.New(),
+	}, nil
+}
+
+// LimitFor returns the limit of a route pattern.
+func (l *Limiter) LimitFor(route string) Limit {
+	if limit, exists := l.routeLimits[route]; exists {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// ClientKey identifies the client of a request: by API token if it sent a
+// valid one, by IP otherwise. Invalid tokens fall back to the IP, so guessing
+// tokens doesn't buy extra requests.
+func (l *Limiter) ClientKey(r *http.Request) string {
+	bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if found && l.apiToken != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(l.apiToken)) == 1 {
+		fingerprint := sha256.Sum256([]byte(bearer))
+		return "token:" + hex.EncodeToString(fingerprint[:8])
+	}
+	return "ip:" + clientNetwork(l.clientIPs.ClientIP(r))
+}
+
+var ipv6ClientMask = net.CIDRMask(64, 128)
+
+// clientNetwork returns the IPv4 address of a client, or the /64 prefix of
+// its IPv6 address. A single IPv6 client usually gets a whole /64, so keying
+// by address would give it billions of buckets.
+func clientNetwork(ipStr string) string {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return ipStr
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+
+	network := &net.IPNet{IP: ip.Mask(ipv6ClientMask), Mask: ipv6ClientMask}
+	return network.String()
+}
+
+// Allow takes a token from the bucket of client for route, if there's any.
+func (l *Limiter) Allow(route string, client string) Result {
+	return l.allow(route, client, time.Now())
+}
+
+func (l *Limiter) allow(route string, client string, now time.Time) Result {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.evict(now)
+
+	b := l.getBucket(route+" "+client, l.LimitFor(route), now)
+	b.refill(now)
+
+	result := Result{Limit: b.limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(b.limit.refillInterval()))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(b.limit.Requests) - b.tokens) * float64(b.limit.refillInterval()))
+	return result
+}
+
+// getBucket returns the bucket of key, creating a full one if it doesn't exist.
+func (l *Limiter) getBucket(key string, limit Limit, now time.Time) *bucket {
+	if element, exists := l.buckets[key]; exists {
+		l.lru.MoveToFront(element)
+		return element.Value.(*bucket)
+	}
+
+	// Under a flood of new clients, the least recently used buckets go first.
+	for l.lru.Len() >= l.maxKeys {
+		l.remove(l.lru.Back())
+	}
+
+	b := &bucket{
+		key:       key,
+		limit:     limit,
+		tokens:    float64(limit.Requests),
+		updatedAt: now,
+	}
+	l.buckets[key] = l.lru.PushFront(b)
+	return b
+}
+
+// evict removes the least recently used buckets that are full again.
+func (l *Limiter) evict(now time.Time) {
+	for element := l.lru.Back(); element != nil; element = l.lru.Back() {
+		b := element.Value.(*bucket)
+		if now.Sub(b.updatedAt) < b.limit.Period {
+			return
+		}
+		l.remove(element)
+	}
+}
+
+func (l *Limiter) remove(element *list.Element) {
+	l.lru.Remove(element)
+	delete(l.buckets, element.Value.(*bucket).key)
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt)
+	b.tokens = min(float64(b.limit.Requests), b.tokens+float64(elapsed)/float64(b.limit.refillInterval()))
+	b.updatedAt = now
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+)
+
+func newTestLimiter(t *testing.T, maxKeys int) *Limiter {
+	t.Helper()
+
+	limiter, err := NewLimiter(config.RateLimit{
+		Default: "2/1m",
+		Routes:  []string{"POST /ideas=1/1h"},
+		MaxKeys: maxKeys,
+	}, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return limiter
+}
+
+func TestAllowRefills(t *testing.T) {
+	limiter := newTestLimiter(t, 10)
+	start := time.Now()
+
+	steps := []struct {
+		after     time.Duration
+		allowed   bool
+		remaining int
+	}{
+		{after: 0, allowed: true, remaining: 1},
+		{after: 0, allowed: true, remaining: 0},
+		{after: 0, allowed: false, remaining: 0},
+		// A request is earned back every 30s.
+		{after: 29 * time.Second, allowed: false, remaining: 0},
+		{after: 30 * time.Second, allowed: true, remaining: 0},
+		// The bucket never holds more than the limit.
+		{after: time.Hour, allowed: true, remaining: 1},
+	}
+
+	for i, step := range steps {
+		result := limiter.allow("GET /ideas", "ip:198.51.100.1", start.Add(step.after))
+		if result.Allowed != step.allowed || result.Remaining != step.remaining {
+			t.Errorf("step %d: allowed = %v with %d remaining, want %v with %d", i, result.Allowed, result.Remaining, step.allowed, step.remaining)
+		}
+	}
+}
+
+func TestAllowRetryAfter(t *testing.T) {
+	limiter := newTestLimiter(t, 10)
+	now := time.Now()
+
+	limiter.allow("POST /ideas", "ip:198.51.100.1", now)
+	result := limiter.allow("POST /ideas", "ip:198.51.100.1", now.Add(15*time.Minute))
+
+	if result.Allowed {
+		t.Fatal("second request was allowed, want it rejected")
+	}
+	if result.RetryAfter != 45*time.Minute {
+		t.Errorf("RetryAfter = %v, want 45m", result.RetryAfter)
+	}
+	if result.Limit.Requests != 1 || result.Limit.Period != time.Hour {
+		t.Errorf("Limit = %+v, want the route limit 1/1h", result.Limit)
+	}
+}
+
+func TestAllowSeparatesRoutesAndClients(t *testing.T) {
+	limiter := newTestLimiter(t, 10)
+	now := time.Now()
+
+	limiter.allow("POST /ideas", "ip:198.51.100.1", now)
+	if !limiter.allow("POST /ideas", "ip:198.51.100.2", now).Allowed {
+		t.Error("another client was rejected")
+	}
+	if !limiter.allow("GET /ideas", "ip:198.51.100.1", now).Allowed {
+		t.Error("another route was rejected")
+	}
+}
+
+func TestEviction(t *testing.T) {
+	limiter := newTestLimiter(t, 2)
+	now := time.Now()
+
+	// Full buckets are evicted once they're idle for the limit period.
+	limiter.allow("GET /ideas", "ip:198.51.100.1", now)
+	limiter.allow("GET /ideas", "ip:198.51.100.2", now.Add(30*time.Second))
+	limiter.allow("GET /ideas", "ip:198.51.100.3", now.Add(time.Minute))
+	if _, exists := limiter.buckets["GET /ideas ip:198.51.100.1"]; exists {
+		t.Error("idle bucket wasn't evicted")
+	}
+
+	// Past the maximum, the least recently used bucket goes, even if it's
+	// not full yet.
+	limiter.allow("GET /ideas", "ip:198.51.100.2", now.Add(time.Minute))
+	limiter.allow("GET /ideas", "ip:198.51.100.4", now.Add(time.Minute))
+	if len(limiter.buckets) != 2 {
+		t.Errorf("%d buckets are kept, want 2", len(limiter.buckets))
+	}
+	if _, exists := limiter.buckets["GET /ideas ip:198.51.100.3"]; exists {
+		t.Error("least recently used bucket wasn't evicted")
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	limiter := newTestLimiter(t, 10)
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		authorization string
+		want          string
+	}{
+		{name: "IPv4", remoteAddr: "198.51.100.1:1234", want: "ip:198.51.100.1"},
+		{name: "IPv4-mapped IPv6", remoteAddr: "[::ffff:198.51.100.1]:1234", want: "ip:198.51.100.1"},
+		{name: "IPv6 by /64", remoteAddr: "[2001:db8:1:2:3:4:5:6]:1234", want: "ip:2001:db8:1:2::/64"},
+		{name: "valid token", remoteAddr: "198.51.100.1:1234", authorization: "Bearer token", want: "token:3c469e9d6c5875d3"},
+		{name: "invalid token", remoteAddr: "198.51.100.1:1234", authorization: "Bearer guess", want: "ip:198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			if got := limiter.ClientKey(r); got != tt.want {
+				t.Errorf("ClientKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters, with the usual values.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// phraseBoost is added to the score of a document for each phrase it matches.
+	phraseBoost = 1.0
+)
+
+// Index is a thread-safe in-memory inverted index. Documents are ranked with BM25.
+type Index struct {
+	// lock protects everything below.
+	lock sync.RWMutex
+
+	// docs holds the tokens of each document by ID.
+	docs map[string][]Token
+
+	// postings holds the positions of each term in each document, by term and ID.
+	postings map[string]map[string][]int
+
+	// totalLength is the sum of the length of all documents, in tokens.
+	totalLength int
+}
+
+// Match is a document that matches a query.
+type Match struct {
+	ID    string
+	Score float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string][]Token),
+		postings: make(map[string]map[string][]int),
+	}
+}
+
+// Add indexes a document, replacing the previous version of it, if any.
+func (i *Index) Add(id string, text string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.remove(id)
+
+	tokens := Tokenize(text)
+	i.docs[id] = tokens
+	i.totalLength += len(tokens)
+
+	for position, token := range tokens {
+		docPostings, exists := i.postings[token.Term]
+		if !exists {
+			docPostings = make(map[string][]int)
+			i.postings[token.Term] = docPostings
+		}
+		docPostings[id] = append(docPostings[id], position)
+	}
+}
+
+// Remove removes a document from the index, if it exists.
+func (i *Index) Remove(id string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.remove(id)
+}
+
+func (i *Index) remove(id string) {
+	tokens, exists := i.docs[id]
+	if !exists {
+		return
+	}
+
+	for _, token := range tokens {
+		docPostings := i.postings[token.Term]
+		delete(docPostings, id)
+		if len(docPostings) == 0 {
+			delete(i.postings, token.Term)
+		}
+	}
+
+	i.totalLength -= len(tokens)
+	delete(i.docs, id)
+}
+
+// Search returns the documents matching every clause of query, most relevant first.
+func (i *Index) Search(query *Query) []*Match {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	if query.IsEmpty() || len(i.docs) == 0 {
+		return nil
+	}
+
+	var matches []*Match
+	for id := range i.candidates(query) {
+		phrases, ok := i.matchClauses(id, query)
+		if !ok {
+			continue
+		}
+
+		matches = append(matches, &Match{
+			ID:    id,
+			Score: i.score(id, query) + phraseBoost*float64(phrases),
+		})
+	}
+
+	// Ties are broken by ID, so results are stable.
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+		return matches[a].ID < matches[b].ID
+	})
+	return matches
+}
+
+// candidates returns the documents having the rarest term of the query. Every
+// match must have it.
+func (i *Index) candidates(query *Query) map[string][]int {
+	var rarest map[string][]int
+	for _, term := range query.terms() {
+		docPostings := i.postings[term]
+		if rarest == nil || len(docPostings) < len(rarest) {
+			rarest = docPostings
+		}
+	}
+	return rarest
+}
+
+// matchClauses tells whether document id matches every clause of query, and
+// how many of them are phrases.
+func (i *Index) matchClauses(id string, query *Query) (int, bool) {
+	phrases := 0
+	for _, clause := range query.Clauses {
+		if !i.hasPhrase(id, clause) {
+			return 0, false
+		}
+		if len(clause) > 1 {
+			phrases++
+		}
+	}
+	return phrases, true
+}
+
+// hasPhrase tells whether the terms appear in a row in document id.
+func (i *Index) hasPhrase(id string, terms []string) bool {
+	for _, start := range i.postings[terms[0]][id] {
+		if i.phraseAt(id, terms, start) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Index) phraseAt(id string, terms []string, start int) bool {
+	tokens := i.docs[id]
+	if start+len(terms) > len(tokens) {
+		return false
+	}
+
+	for offset, term := range terms {
+		if tokens[start+offset].Term != term {
+			return false
+		}
+	}
+	return true
+}
+
+// score is the BM25 score of document id for the terms of query.
+func (i *Index) score(id string, query *Query) float64 {
+	docCount := float64(len(i.docs))
+	avgLength := float64(i.totalLength) / docCount
+	length := float64(len(i.docs[id]))
+
+	score := 0.0
+	for _, term := range query.terms() {
+		docPostings := i.postings[term]
+		frequency := float64(len(docPostings[id]))
+		if frequency == 0 {
+			continue
+		}
+
+		idf := math.Log(1 + (docCount-float64(len(docPostings))+0.5)/(float64(len(docPostings))+0.5))
+		score += idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*(1-bm25B+bm25B*length/avgLength))
+	}
+	return score
+}
+
+// Highlight escapes text as HTML and wraps the words matching query in <mark>.
+func Highlight(text string, query *Query) string {
+	terms := make(map[string]bool)
+	for _, term := range query.terms() {
+		terms[term] = true
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, token := range Tokenize(text) {
+		if !terms[token.Term] {
+			continue
+		}
+
+		sb.WriteString(html.EscapeString(text[last:token.Start]))
+		sb.WriteString("<mark>")
+		sb.WriteString(html.EscapeString(text[token.Start:token.End]))
+		sb.WriteString("</mark>")
+		last = token.End
+	}
+	sb.WriteString(html.EscapeString(text[last:]))
+
+	return sb.String()
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestIndex(docs map[string]string) *Index {
+	index := NewIndex()
+	for id, text := range docs {
+		index.Add(id, text)
+	}
+	return index
+}
+
+func matchIDs(matches []*Match) []string {
+	ids := []string{}
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := newTestIndex(map[string]string{
+		"rare":      "Go has generics now",
+		"frequent":  "Go go go, everyone says go",
+		"long":      "Go is a language with a garbage collector, goroutines, channels and generics",
+		"phrase":    "Generic types in Go, generic types everywhere",
+		"scattered": "Types that are generic in Go",
+		"unrelated": "Tea at five",
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			// Documents with more occurrences rank higher, shorter ones too.
+			name:  "term frequency and length",
+			query: "go",
+			want:  []string{"frequent", "rare", "scattered", "phrase", "long"},
+		},
+		{
+			// Rarer terms weigh more than frequent ones.
+			name:  "every term",
+			query: "go generics",
+			want:  []string{"rare", "long"},
+		},
+		{
+			name:  "phrase",
+			query: `"generic types"`,
+			want:  []string{"phrase"},
+		},
+		{
+			name:  "no match",
+			query: "coffee",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchIDs(index.Search(ParseQuery(tt.query))); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexPhraseBoost(t *testing.T) {
+	index := newTestIndex(map[string]string{
+		"together": "generic types in go",
+		"apart":    "types go in generic",
+	})
+
+	// Both have the same words, so only phrases tell them apart.
+	matches := index.Search(ParseQuery("generic types"))
+	if got := matchIDs(matches); !reflect.DeepEqual(got, []string{"apart", "together"}) {
+		t.Fatalf("Search() = %q, want ties sorted by ID", got)
+	}
+	words := matches[1].Score
+
+	matches = index.Search(ParseQuery(`"generic types"`))
+	if got := matchIDs(matches); !reflect.DeepEqual(got, []string{"together"}) {
+		t.Fatalf("Search() = %q, want only the phrase", got)
+	}
+	if want := words + phraseBoost; matches[0].Score != want {
+		t.Errorf("score of the phrase = %v, want %v", matches[0].Score, want)
+	}
+}
+
+func TestIndexAddAndRemove(t *testing.T) {
+	index := newTestIndex(map[string]string{
+		"a": "first version",
+		"b": "another document",
+	})
+
+	// Adding a document again replaces it.
+	index.Add("a", "second version")
+	if got := matchIDs(index.Search(ParseQuery("first"))); len(got) != 0 {
+		t.Errorf("Search(first) = %q, want the old version gone", got)
+	}
+	if got := matchIDs(index.Search(ParseQuery("version"))); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Search(version) = %q, want [a]", got)
+	}
+
+	index.Remove("a")
+	index.Remove("missing")
+	if got := matchIDs(index.Search(ParseQuery("version"))); len(got) != 0 {
+		t.Errorf("Search(version) = %q, want nothing after removing it", got)
+	}
+	if index.totalLength != 2 {
+		t.Errorf("totalLength = %d, want 2, the length of b", index.totalLength)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("Café <b>& crème</b>", ParseQuery("cafe creme"))
+	want := "<mark>Café</mark> &lt;b&gt;&amp; <mark>crème</mark>&lt;/b&gt;"
+	if got != want {
+		t.Errorf("Highlight() = %q, want %q", got, want)
+	}
+}
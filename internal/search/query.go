@@ -0,0 +1,58 @@
+package search
+
+import "strings"
+
+// Query is a parsed search query. Every clause must match.
+type Query struct {
+	// Clauses are single terms or phrases, i.e. terms that must appear in a row.
+	Clauses [][]string
+}
+
+// ParseQuery parses queries like `go "generic types"`: quoted text is a
+// phrase, everything else is single terms. An unclosed quote runs until the end.
+func ParseQuery(q string) *Query {
+	query := &Query{}
+
+	for i, part := range strings.Split(q, `"`) {
+		isPhrase := i%2 == 1
+
+		var terms []string
+		for _, token := range Tokenize(part) {
+			terms = append(terms, token.Term)
+		}
+		if len(terms) == 0 {
+			continue
+		}
+
+		if isPhrase {
+			query.Clauses = append(query.Clauses, terms)
+			continue
+		}
+		for _, term := range terms {
+			query.Clauses = append(query.Clauses, []string{term})
+		}
+	}
+
+	return query
+}
+
+// IsEmpty tells whether the query has nothing to search for.
+func (q *Query) IsEmpty() bool {
+	return len(q.Clauses) == 0
+}
+
+// terms returns every distinct term of the query.
+func (q *Query) terms() []string {
+	seen := make(map[string]bool)
+
+	var terms []string
+	for _, clause := range q.Clauses {
+		for _, term := range clause {
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
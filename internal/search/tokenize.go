@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a word of a text, normalized for matching.
+type Token struct {
+	Term string
+
+	// Start and End are byte offsets of the word in the original text.
+	Start int
+	End   int
+}
+
+// Tokenize splits text into words, i.e. runs of letters and digits. Terms are
+// lowercased and stripped of accents, so "Café" matches "cafe".
+func Tokenize(text string) []Token {
+	var tokens []Token
+
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if isWordRune && start < 0 {
+			start = i
+		}
+		if !isWordRune && start >= 0 {
+			tokens = append(tokens, newToken(text, start, i))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, newToken(text, start, len(text)))
+	}
+
+	return tokens
+}
+
+func newToken(text string, start int, end int) Token {
+	return Token{
+		Term:  Normalize(text[start:end]),
+		Start: start,
+		End:   end,
+	}
+}
+
+// Normalize lowercases s and removes its accents.
+func Normalize(s string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Token
+	}{
+		{
+			name: "empty",
+			text: "",
+		},
+		{
+			name: "punctuation and spaces",
+			text: "Go, generics!  v2",
+			want: []Token{{"go", 0, 2}, {"generics", 4, 12}, {"v2", 15, 17}},
+		},
+		{
+			name: "accents and case",
+			text: "Café CRÈME",
+			want: []Token{{"cafe", 0, 5}, {"creme", 6, 12}},
+		},
+		{
+			name: "combining accents",
+			text: "café au lait",
+			want: []Token{{"cafe", 0, 6}, {"au", 7, 9}, {"lait", 10, 14}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  [][]string
+	}{
+		{query: "Go Generics", want: [][]string{{"go"}, {"generics"}}},
+		{query: `go "generic TYPES"`, want: [][]string{{"go"}, {"generic", "types"}}},
+		{query: `"unclosed phrase`, want: [][]string{{"unclosed", "phrase"}}},
+		{query: `"" !?`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query := ParseQuery(tt.query)
+			if !reflect.DeepEqual(query.Clauses, tt.want) {
+				t.Errorf("ParseQuery(%q) = %q, want %q", tt.query, query.Clauses, tt.want)
+			}
+			if query.IsEmpty() != (len(tt.want) == 0) {
+				t.Errorf("ParseQuery(%q).IsEmpty() = %v", tt.query, query.IsEmpty())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/config"
+)
+
+// CORSMaxAge is how long browsers can cache preflight responses.
+const CORSMaxAge = time.Hour
+
+// CORS is a middleware that handles Cross-Origin Resource Sharing for all routes,
+// based on an allowlist of origins.
+type CORS struct {
+	allowAnyOrigin   bool
+	allowedOrigins   []string
+	wildcardOrigins  []wildcardOrigin
+	allowCredentials bool
+}
+
+// CORSRoute holds the CORS policy of a single route.
+type CORSRoute struct {
+	// Methods allowed cross-origin. Simple methods still need to be listed.
+	Methods []string
+
+	// Headers that cross-origin requests are allowed to send, besides the
+	// CORS-safelisted ones.
+	Headers []string
+}
+
+// wildcardOrigin matches any subdomain of an origin, e.g. "https://*.joaohenri.io".
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+func NewCORS(cfg config.CORS) *CORS {
+	c := &CORS{
+		allowCredentials: cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		switch {
+		case origin == "*":
+			c.allowAnyOrigin = true
+		case strings.Contains(origin, "://*."):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			c.wildcardOrigins = append(c.wildcardOrigins, wildcardOrigin{prefix: prefix, suffix: suffix})
+		default:
+			c.allowedOrigins = append(c.allowedOrigins, origin)
+		}
+	}
+
+	return c
+}
+
+// Handler wraps next with the CORS policy of a route. Preflight requests are
+// answered directly, without reaching next.
+// The route is read on every request, so it can still be extended after the
+// handler is created.
+func (c *CORS) Handler(route *CORSRoute, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		// Responses differ by origin, so caches must not mix them up.
+		if !c.allowAnyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		// Same-origin and non-browser requests don't need CORS.
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !c.isOriginAllowed(origin) {
+			if preflight {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+
+			// Browsers block the response by themselves due to the missing headers.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.allowAnyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !slices.Contains(route.Methods, r.Header.Get("Access-Control-Request-Method")) {
+			http.Error(w, "method not allowed", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(route.Methods, ", "))
+		if len(route.Headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(route.Headers, ", "))
+		}
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(CORSMaxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *CORS) isOriginAllowed(origin string) bool {
+	if c.allowAnyOrigin || slices.Contains(c.allowedOrigins, origin) {
+		return true
+	}
+
+	for _, wildcard := range c.wildcardOrigins {
+		if wildcard.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches accepts exactly one or more subdomain labels in place of the wildcard.
+// E.g. "https://*.joaohenri.io" matches "https://www.joaohenri.io", but neither
+// "https://joaohenri.io" nor "https://evil.com/.joaohenri.io".
+func (w wildcardOrigin) matches(origin string) bool {
+	if !strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+		return false
+	}
+
+	if len(origin) <= len(w.prefix)+len(w.suffix) {
+		return false
+	}
+
+	subdomain := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+	return !strings.ContainsAny(subdomain, "/:@?#")
+}
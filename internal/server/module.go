@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/health"
+	"github.com/jaehnri/website-backend/pkg/status"
+)
+
+// Module is a feature of the server, e.g. Spotify or ideas. Modules can be
+// disabled in the configuration and fail independently: a module that is
+// misconfigured or fails to start is reported as degraded, while the others
+// keep working.
+type Module interface {
+	// Name identifies the module in logs and GET /status.
+	Name() string
+
+	// Enabled tells whether the module was turned on in the configuration.
+	Enabled(cfg *config.Config) bool
+
+	// Validate reports every problem of the configuration of the module. It's
+	// only called if the module is enabled.
+	Validate(cfg *config.Config) error
+
+	// Init builds the module clients. Modules are initialized in registration
+	// order, so a module can look up the ones registered before it.
+	Init(cfg *config.Config, registry *Registry) error
+
+	// Routes registers the module routes, either publicly or requiring the API token.
+	Routes(public *Router, authenticated *Router)
+
+	// Run runs background workers, if any, until ctx is cancelled.
+	Run(ctx context.Context)
+}
+
+// HealthCheckedModule is a Module with dependencies checked by GET /readyz.
+type HealthCheckedModule interface {
+	Module
+
+	// HealthChecks returns the checks of the module dependencies, e.g. whether
+	// its storage is reachable.
+	HealthChecks() []health.Check
+}
+
+// Registry holds all modules of the server and their status.
+type Registry struct {
+	modules []*registeredModule
+}
+
+type registeredModule struct {
+	module Module
+	status string
+	err    error
+}
+
+func NewRegistry(modules ...Module) *Registry {
+	r := &Registry{}
+	for _, module := range modules {
+		r.modules = append(r.modules, &registeredModule{
+			module: module,
+			status: status.ModuleDisabled,
+		})
+	}
+	return r
+}
+
+// Init validates and initializes all enabled modules. Invalid configurations
+// and failures, including panics, only degrade the failing module.
+func (r *Registry) Init(cfg *config.Config) {
+	for _, m := range r.modules {
+		if !m.module.Enabled(cfg) {
+			slog.Info("module is disabled", "module", m.module.Name())
+			continue
+		}
+
+		err := m.module.Validate(cfg)
+		if err != nil {
+			err = fmt.Errorf("invalid configuration: %w", err)
+		} else {
+			err = initModule(m.module, cfg, r)
+		}
+		if err != nil {
+			slog.Error("module is degraded", "module", m.module.Name(), "err", err)
+			m.status = status.ModuleDegraded
+			m.err = err
+			continue
+		}
+
+		slog.Info("module is enabled", "module", m.module.Name())
+		m.status = status.ModuleEnabled
+	}
+}
+
+func initModule(module Module, cfg *config.Config, registry *Registry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during init: %v", r)
+		}
+	}()
+
+	return module.Init(cfg, registry)
+}
+
+// Get returns a module by name, only if it's enabled and healthy.
+func (r *Registry) Get(name string) (Module, bool) {
+	for _, m := range r.modules {
+		if m.module.Name() == name && m.status == status.ModuleEnabled {
+			return m.module, true
+		}
+	}
+	return nil, false
+}
+
+// Routes registers the routes of all enabled modules.
+func (r *Registry) Routes(public *Router, authenticated *Router) {
+	for _, m := range r.modules {
+		if m.status == status.ModuleEnabled {
+			m.module.Routes(public, authenticated)
+		}
+	}
+}
+
+// Run runs the background workers of all enabled modules until ctx is cancelled.
+func (r *Registry) Run(ctx context.Context) {
+	for _, m := range r.modules {
+		if m.status == status.ModuleEnabled {
+			go m.module.Run(ctx)
+		}
+	}
+}
+
+// HealthChecks returns the health checks of all enabled modules. Degraded
+// modules are reported as degraded, but don't fail readiness, as the other
+// modules still work.
+func (r *Registry) HealthChecks() []health.Check {
+	var checks []health.Check
+	for _, m := range r.modules {
+		if m.status == status.ModuleDegraded {
+			checks = append(checks, health.Check{
+				Name:     m.module.Name() + "_module",
+				Check:    func(context.Context) error { return m.err },
+				Optional: true,
+			})
+			continue
+		}
+		if m.status != status.ModuleEnabled {
+			continue
+		}
+		if module, ok := m.module.(HealthCheckedModule); ok {
+			checks = append(checks, module.HealthChecks()...)
+		}
+	}
+	return checks
+}
+
+// HandleStatus receives an HTTP request and returns the status of every module
+// in StatusResponse format.
+func (r *Registry) HandleStatus(w http.ResponseWriter, req *http.Request) {
+	response := &status.StatusResponse{}
+	for _, m := range r.modules {
+		module := &status.Module{
+			Name:   m.module.Name(),
+			Status: m.status,
+		}
+		if m.err != nil {
+			module.Error = m.err.Error()
+		}
+		response.Modules = append(response.Modules, module)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	err := encoder.Encode(response)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
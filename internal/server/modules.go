@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/health"
+	"github.com/jaehnri/website-backend/internal/ideas"
+	"github.com/jaehnri/website-backend/internal/listening"
+	"github.com/jaehnri/website-backend/internal/spotify"
+)
+
+// spotifyModule serves Spotify endpoints other than /now-playing, and shares
+// its client with the now playing and listening modules.
+type spotifyModule struct {
+	client *spotify.SpotifyClient
+}
+
+func (m *spotifyModule) Name() string {
+	return "spotify"
+}
+
+func (m *spotifyModule) Enabled(cfg *config.Config) bool {
+	return cfg.Spotify.Enabled
+}
+
+func (m *spotifyModule) Validate(cfg *config.Config) error {
+	return cfg.ValidateSpotify()
+}
+
+func (m *spotifyModule) Init(cfg *config.Config, _ *Registry) error {
+	m.client = spotify.NewSpotifyClient(cfg.Spotify)
+	return nil
+}
+
+func (m *spotifyModule) Routes(public *Router, _ *Router) {
+	public.HandleFunc("GET /recently-played", m.client.HandleRecentlyPlayed)
+	public.HandleFunc("GET /playlists", m.client.HandlePlaylists)
+	public.HandleFunc("GET /playlists/{id}", m.client.HandlePlaylist)
+}
+
+func (m *spotifyModule) Run(context.Context) {}
+
+func (m *spotifyModule) HealthChecks() []health.Check {
+	return []health.Check{{Name: "spotify_token", Check: m.client.CheckAccessToken}}
+}
+
+// getSpotifyClient returns the client of the Spotify module, if it's enabled and healthy.
+func getSpotifyClient(registry *Registry) (*spotify.SpotifyClient, error) {
+	module, ok := registry.Get("spotify")
+	if !ok {
+		return nil, errors.New("spotify module is not available")
+	}
+	return module.(*spotifyModule).client, nil
+}
+
+// nowPlayingModule serves /now-playing from a fallback chain of providers.
+type nowPlayingModule struct {
+	provider NowPlayingProvider
+}
+
+func (m *nowPlayingModule) Name() string {
+	return "now_playing"
+}
+
+func (m *nowPlayingModule) Enabled(cfg *config.Config) bool {
+	return cfg.NowPlaying.Enabled
+}
+
+func (m *nowPlayingModule) Validate(cfg *config.Config) error {
+	return cfg.ValidateNowPlaying()
+}
+
+func (m *nowPlayingModule) Init(cfg *config.Config, registry *Registry) error {
+	provider, err := newNowPlayingProvider(cfg, registry)
+	if err != nil {
+		return err
+	}
+
+	m.provider = provider
+	return nil
+}
+
+func (m *nowPlayingModule) Routes(public *Router, _ *Router) {
+	public.HandleFunc("GET /now-playing", m.HandleNowPlaying)
+}
+
+func (m *nowPlayingModule) Run(context.Context) {}
+
+// ideasModule serves /ideas.
+type ideasModule struct {
+	client *ideas.IdeasClient
+}
+
+func (m *ideasModule) Name() string {
+	return "ideas"
+}
+
+func (m *ideasModule) Enabled(cfg *config.Config) bool {
+	return cfg.Ideas.Enabled
+}
+
+func (m *ideasModule) Validate(cfg *config.Config) error {
+	return cfg.ValidateIdeas()
+}
+
+func (m *ideasModule) Init(cfg *config.Config, _ *Registry) error {
+	client, err := ideas.NewIdeasClient(cfg.Ideas)
+	if err != nil {
+		return err
+	}
+
+	m.client = client
+	return nil
+}
+
+func (m *ideasModule) Routes(public *Router, authenticated *Router) {
+	public.HandleFunc("GET /ideas", m.client.HandleGetIdeas)
+	public.HandleFunc("GET "+ideas.RSSPath, m.client.HandleRSS)
+	public.HandleFunc("GET "+ideas.AtomPath, m.client.HandleAtom)
+	public.HandleFunc("GET "+ideas.JSONFeedPath, m.client.HandleJSONFeed)
+	public.HandleFunc("GET "+ideas.TagsPath, m.client.HandleTags)
+	public.HandleFunc("GET "+ideas.TagRSSPath, m.client.HandleRSS)
+	public.HandleFunc("GET "+ideas.TagAtomPath, m.client.HandleAtom)
+	public.HandleFunc("GET "+ideas.TagJSONFeedPath, m.client.HandleJSONFeed)
+	authenticated.HandleFunc("POST /ideas", m.client.HandlePostIdeas)
+	authenticated.HandleFunc("GET "+ideas.DraftsPath, m.client.HandleGetDrafts)
+	authenticated.HandleFunc("DELETE "+ideas.IdeaPath, m.client.HandleDeleteIdea)
+	authenticated.HandleFunc("GET "+ideas.TrashPath, m.client.HandleGetTrash)
+	authenticated.HandleFunc("POST "+ideas.RestorePath, m.client.HandleRestoreIdea)
+	authenticated.HandleFunc("GET "+ideas.RevisionsPath, m.client.HandleGetRevisions)
+	authenticated.HandleFunc("GET "+ideas.RevisionPath, m.client.HandleGetRevision)
+	authenticated.HandleFunc("POST "+ideas.RestoreRevisionPath, m.client.HandleRestoreRevision)
+}
+
+func (m *ideasModule) Run(ctx context.Context) {
+	m.client.WarmUp(ctx)
+
+	go m.client.RunPurger(ctx)
+	go m.client.RunCompactor(ctx)
+	m.client.RunScheduler(ctx)
+}
+
+func (m *ideasModule) HealthChecks() []health.Check {
+	return []health.Check{{Name: "ideas_storage", Check: m.client.CheckStorage}}
+}
+
+// listeningModule snapshots my Spotify top items every month and serves them.
+type listeningModule struct {
+	client *listening.ListeningClient
+}
+
+func (m *listeningModule) Name() string {
+	return "listening"
+}
+
+func (m *listeningModule) Enabled(cfg *config.Config) bool {
+	return cfg.Listening.Enabled
+}
+
+func (m *listeningModule) Validate(cfg *config.Config) error {
+	return cfg.ValidateListening()
+}
+
+func (m *listeningModule) Init(cfg *config.Config, registry *Registry) error {
+	spotifyClient, err := getSpotifyClient(registry)
+	if err != nil {
+		return err
+	}
+
+	client, err := listening.NewListeningClient(cfg.Listening, spotifyClient)
+	if err != nil {
+		return err
+	}
+
+	m.client = client
+	return nil
+}
+
+func (m *listeningModule) Routes(public *Router, _ *Router) {
+	public.HandleFunc("GET /listening/monthly/{month}", m.client.HandleMonthly)
+	public.HandleFunc("GET /listening/wrapped/{year}", m.client.HandleWrapped)
+}
+
+func (m *listeningModule) Run(ctx context.Context) {
+	m.client.RunSnapshots(ctx)
+}
+
+func (m *listeningModule) HealthChecks() []health.Check {
+	return []health.Check{{Name: "listening_snapshots", Check: m.client.CheckSnapshots}}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/lastfm"
+	"github.com/jaehnri/website-backend/internal/listenbrainz"
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+)
+
+// NowPlayingProvider is any music service that knows what I'm listening to.
+type NowPlayingProvider interface {
+	// Name identifies the provider in logs and errors.
+	Name() string
+
+	// GetNowPlaying returns the current playing song or, if there's none,
+	// the last played one.
+	GetNowPlaying(ctx context.Context) (*nowplaying.CurrentSong, error)
+}
+
+// DetailedNowPlayingProvider is a NowPlayingProvider that also knows the playback
+// context, device and shuffle/repeat state.
+type DetailedNowPlayingProvider interface {
+	NowPlayingProvider
+
+	// GetNowPlayingDetail works like GetNowPlaying, but fills CurrentSong.Detail.
+	GetNowPlayingDetail(ctx context.Context) (*nowplaying.CurrentSong, error)
+}
+
+// FallbackNowPlayingProvider tries each provider in order, returning the
+// first successful answer. This way the site still shows music when a single
+// provider is down.
+type FallbackNowPlayingProvider struct {
+	providers []NowPlayingProvider
+}
+
+func NewFallbackNowPlayingProvider(providers ...NowPlayingProvider) *FallbackNowPlayingProvider {
+	return &FallbackNowPlayingProvider{
+		providers: providers,
+	}
+}
+
+func (f *FallbackNowPlayingProvider) Name() string {
+	names := make([]string, 0, len(f.providers))
+	for _, provider := range f.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *FallbackNowPlayingProvider) GetNowPlaying(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	return f.getNowPlaying(ctx, false)
+}
+
+// GetNowPlayingDetail asks each provider for the playback detail. Providers that
+// don't support it still answer, just without CurrentSong.Detail.
+func (f *FallbackNowPlayingProvider) GetNowPlayingDetail(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	return f.getNowPlaying(ctx, true)
+}
+
+func (f *FallbackNowPlayingProvider) getNowPlaying(ctx context.Context, detail bool) (*nowplaying.CurrentSong, error) {
+	var errs []error
+	for _, provider := range f.providers {
+		song, err := getNowPlaying(ctx, provider, detail)
+		if err == nil {
+			return song, nil
+		}
+
+		slog.WarnContext(ctx, "now playing provider failed, trying next one", "provider", provider.Name(), "err", err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("no now playing providers configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+func getNowPlaying(ctx context.Context, provider NowPlayingProvider, detail bool) (*nowplaying.CurrentSong, error) {
+	if detailedProvider, ok := provider.(DetailedNowPlayingProvider); ok && detail {
+		return detailedProvider.GetNowPlayingDetail(ctx)
+	}
+	return provider.GetNowPlaying(ctx)
+}
+
+// newNowPlayingProvider builds the fallback chain of the configured providers.
+// The Spotify client is shared with the Spotify module, so the Spotify provider
+// is skipped when that module isn't available.
+func newNowPlayingProvider(cfg *config.Config, registry *Registry) (NowPlayingProvider, error) {
+	var providers []NowPlayingProvider
+	for _, name := range cfg.NowPlaying.Providers {
+		switch name {
+		case "spotify":
+			spotifyClient, err := getSpotifyClient(registry)
+			if err != nil {
+				slog.Warn("skipping now playing provider", "provider", name, "err", err)
+				continue
+			}
+			providers = append(providers, spotifyClient)
+		case "lastfm":
+			providers = append(providers, lastfm.NewLastFMClient(cfg.LastFM))
+		case "listenbrainz":
+			providers = append(providers, listenbrainz.NewListenBrainzClient(cfg.ListenBrainz))
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.New("no now playing providers are available")
+	}
+	return NewFallbackNowPlayingProvider(providers...), nil
+}
+
+// HandleNowPlaying receives an HTTP request and returns the current playing song
+// in CurrentSong format. Use ?detail=full to include the playback detail.
+func (m *nowPlayingModule) HandleNowPlaying(w http.ResponseWriter, r *http.Request) {
+	detail := r.URL.Query().Get("detail") == "full"
+	playingSong, err := getNowPlaying(r.Context(), m.provider, detail)
+	if err != nil {
+		http.Error(w, "failed to fetch current playing song", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(playingSong)
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Router registers routes on a ServeMux, applying the middlewares of its group.
+// Routes use Go 1.22+ patterns, e.g. "GET /ideas/{id}".
+type Router struct {
+	mux         *http.ServeMux
+	middlewares []Middleware
+
+	// cors is nil when routes of this group can't be called cross-origin.
+	cors        *CORS
+	corsHeaders []string
+
+	// corsRoutes holds the CORS policy of each path, shared by all groups.
+	// Allowed methods are collected from the routes registered for the path.
+	corsRoutes map[string]*CORSRoute
+}
+
+func NewRouter(mux *http.ServeMux) *Router {
+	return &Router{
+		mux:        mux,
+		corsRoutes: make(map[string]*CORSRoute),
+	}
+}
+
+// Group returns a router that applies middlewares after the ones of r.
+func (r *Router) Group(middlewares ...Middleware) *Router {
+	group := *r
+	group.middlewares = append(slices.Clone(r.middlewares), middlewares...)
+	return &group
+}
+
+// WithCORS returns a router whose routes can be called cross-origin, allowing
+// requests to send headers besides the CORS-safelisted ones.
+func (r *Router) WithCORS(cors *CORS, headers ...string) *Router {
+	group := *r
+	group.cors = cors
+	group.corsHeaders = headers
+	return &group
+}
+
+// HandleFunc registers handler for pattern, which must include a method.
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	r.Handle(pattern, handler)
+}
+
+// Handle registers handler for pattern, which must include a method.
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	method, path, _ := strings.Cut(pattern, " ")
+
+	// CORS comes first, so preflights don't need to authenticate and errors
+	// are still readable cross-origin.
+	if r.cors != nil {
+		corsRoute := r.corsRoute(path)
+		corsRoute.Methods = append(corsRoute.Methods, method)
+		handler = r.cors.Handler(corsRoute, Chain(r.middlewares...)(handler))
+	} else {
+		handler = Chain(r.middlewares...)(handler)
+	}
+
+	r.mux.Handle(pattern, handler)
+}
+
+// corsRoute returns the CORS policy of path, registering a route to answer
+// its preflight requests the first time.
+func (r *Router) corsRoute(path string) *CORSRoute {
+	corsRoute, exists := r.corsRoutes[path]
+	if !exists {
+		corsRoute = &CORSRoute{}
+		r.corsRoutes[path] = corsRoute
+		r.mux.Handle(http.MethodOptions+" "+path, r.cors.Handler(corsRoute, http.HandlerFunc(handleOptions)))
+	}
+
+	for _, header := range r.corsHeaders {
+		if !slices.Contains(corsRoute.Headers, header) {
+			corsRoute.Headers = append(corsRoute.Headers, header)
+		}
+	}
+	return corsRoute
+}
+
+// handleOptions answers OPTIONS requests that aren't CORS preflights.
+func handleOptions(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
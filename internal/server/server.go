@@ -1,41 +1,127 @@
 package server
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/jaehnri/website-backend/internal/ideas"
-	"github.com/jaehnri/website-backend/internal/spotify"
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/health"
+	"github.com/jaehnri/website-backend/internal/metrics"
+	"github.com/jaehnri/website-backend/internal/ratelimit"
+	"github.com/jaehnri/website-backend/internal/tracing"
 )
 
 type Server struct {
-	httpAddress   string
-	spotifyClient *spotify.SpotifyClient
-	ideasClient   *ideas.IdeasClient
+	cfg      *config.Config
+	cors     *CORS
+	registry *Registry
+	checker  *health.Checker
+
+	// limiter is nil when rate limiting is disabled.
+	limiter *ratelimit.Limiter
 }
 
-func NewServer(httpAddress string) *Server {
+func NewServer(cfg *config.Config) *Server {
+	if cfg.APIToken == "" {
+		slog.Warn("no API token is configured, authenticated routes will reject every request")
+	}
+
+	// Modules that depend on others must be registered after them.
+	registry := NewRegistry(
+		&spotifyModule{},
+		&nowPlayingModule{},
+		&ideasModule{},
+		&listeningModule{},
+	)
+	registry.Init(cfg)
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var err error
+		limiter, err = ratelimit.NewLimiter(cfg.RateLimit, cfg.APIToken.Value())
+		if err != nil {
+			slog.Error("failed to create rate limiter", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	return &Server{
-		httpAddress:   httpAddress,
-		spotifyClient: spotify.NewSpotifyClient(),
-		ideasClient:   ideas.NewIdeasClient(),
+		cfg:      cfg,
+		cors:     NewCORS(cfg.CORS),
+		registry: registry,
+		checker:  health.NewChecker(cfg.Health, registry.HealthChecks()...),
+		limiter:  limiter,
 	}
 }
 
-func (s *Server) startHTTPServer() {
-	log.Println("starting HTTP server")
+// routes builds the handler of the whole API. Middlewares that apply to every
+// request, even unmatched ones, wrap the router itself.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	router := NewRouter(mux)
+
+	// Probes come from the platform, not from browsers.
+	probes := router.Group(Timeout(s.cfg.RequestTimeout))
+	probes.HandleFunc("GET /healthz", s.checker.HandleHealthz)
+	probes.HandleFunc("GET /readyz", s.checker.HandleReadyz)
+
+	// Rate limiting comes before authentication, so guessing the token is throttled too.
+	limited := router.Group(s.rateLimit()...)
+	public := limited.Group(Timeout(s.cfg.RequestTimeout), Compress).WithCORS(s.cors)
+	authenticated := limited.Group(Timeout(s.cfg.RequestTimeout), RequireAPIToken(s.cfg.APIToken.Value())).WithCORS(s.cors, "Authorization", "Content-Type")
+	s.registry.Routes(public, authenticated)
+
+	// Errors of degraded modules might leak details of the deployment.
+	authenticated.HandleFunc("GET /status", s.registry.HandleStatus)
+
+	// Scrapers aren't browsers either, and traffic numbers are nobody else's business.
+	scrapers := router.Group(Timeout(s.cfg.RequestTimeout), RequireAPIToken(s.cfg.APIToken.Value()))
+	scrapers.Handle("GET /metrics", metrics.Handler())
+
+	return Chain(RequestID, AccessLog, tracing.Middleware(routeOf), Metrics, Recover)(mux)
+}
+
+func (s *Server) rateLimit() []Middleware {
+	if s.limiter == nil {
+		return nil
+	}
+	return []Middleware{RateLimit(s.limiter)}
+}
 
-	http.HandleFunc("/now-playing", s.spotifyClient.HandleNowPlaying)
-	http.HandleFunc("/ideas", s.ideasClient.HandleIdeas)
+func (s *Server) startHTTPServer(httpServer *http.Server) {
+	slog.Info("starting HTTP server", "address", httpServer.Addr)
 
-	log.Fatal(http.ListenAndServe(s.httpAddress, nil))
+	err := httpServer.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP server failed", "err", err)
+		os.Exit(1)
+	}
 }
 
 func (s *Server) Run() {
-	go s.startHTTPServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shutdownTracing, err := tracing.Setup(ctx, s.cfg.Tracing)
+	if err != nil {
+		slog.Error("failed to set up tracing", "err", err)
+		os.Exit(1)
+	}
+
+	// Workers start first, so readiness doesn't report them as stopped.
+	s.registry.Run(ctx)
+
+	httpServer := &http.Server{
+		Addr:    s.cfg.HTTPAddress,
+		Handler: s.routes(),
+	}
+	go s.startHTTPServer(httpServer)
 
 	sigint := make(chan os.Signal, 1)
 	// interrupt signal sent from terminal
@@ -44,5 +130,27 @@ func (s *Server) Run() {
 	signal.Notify(sigint, syscall.SIGTERM)
 
 	<-sigint
-	log.Fatal("SIGINT received, shutting down server")
+	slog.Info("termination signal received, shutting down server")
+	s.shutdown(httpServer, shutdownTracing)
+}
+
+// shutdown fails readiness, waits for load balancers to notice, then lets
+// in-flight requests finish and flushes their traces.
+func (s *Server) shutdown(httpServer *http.Server, shutdownTracing func(context.Context) error) {
+	s.checker.SetShuttingDown()
+	time.Sleep(s.cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		slog.Error("failed to shut down HTTP server gracefully", "err", err)
+	}
+
+	err = shutdownTracing(ctx)
+	if err != nil {
+		slog.Error("failed to flush traces", "err", err)
+	}
+	slog.Info("server stopped")
 }
@@ -0,0 +1,95 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jaehnri/website-backend/internal/metrics"
+	"github.com/jaehnri/website-backend/internal/tracing"
+)
+
+// idCollections are path segments followed by a Spotify ID, e.g. /v1/playlists/{id}.
+var idCollections = []string{"playlists", "albums", "artists", "tracks", "users", "shows", "episodes"}
+
+// getJSON fetches a Spotify Web API endpoint and unmarshals its JSON response into v.
+func (s *SpotifyClient) getJSON(ctx context.Context, endpoint string, query url.Values, v any) error {
+	req, err := s.buildAuthorizedRequest(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	if query != nil {
+		req.URL.RawQuery = query.Encode()
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode}
+	}
+
+	return parseJSONResponse(resp, v)
+}
+
+// StatusError is returned when the Spotify Web API answers with an unexpected status.
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("spotify request to %s failed with status %d", e.Endpoint, e.StatusCode)
+}
+
+func (s *SpotifyClient) buildAuthorizedRequest(ctx context.Context, endpoint string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create spotify request", "err", err)
+		return nil, err
+	}
+
+	accessToken, err := s.authProvider.GetAccessToken(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch access token", "err", err)
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return req, nil
+}
+
+func parseJSONResponse(resp *http.Response, v any) error {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bodyBytes, v)
+}
+
+// newTransport instruments requests to both the Web API and the accounts service.
+func newTransport() http.RoundTripper {
+	return tracing.NewTransport(metrics.NewTransport("spotify", endpointOf), endpointOf)
+}
+
+// endpointOf names the endpoint of a request after its path, with IDs
+// replaced, e.g. /v1/playlists/{id}/tracks.
+func endpointOf(r *http.Request) string {
+	segments := strings.Split(r.URL.Path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i] != "" && slices.Contains(idCollections, segments[i-1]) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
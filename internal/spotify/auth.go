@@ -1,17 +1,20 @@
 package spotify
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/internal/metrics"
 	spotifyapi "github.com/jaehnri/website-backend/pkg/spotify"
 )
 
@@ -21,18 +24,9 @@ const (
 	// the next API call.
 	TokenExpiryBuffer = 30 * time.Second
 
-	ClientIDEnv     = "CLIENT_ID_ENV"
-	ClientSecretEnv = "CLIENT_SECRET_ENV"
-	RefreshTokenEnv = "REFRESH_TOKEN_ENV"
-
 	TokenEndpoint = "https://accounts.spotify.com/api/token"
 )
 
-var (
-	once         sync.Once
-	authProvider *AuthProvider
-)
-
 // AuthProvider is a thread-safe module that manages access tokens to the Spotify API.
 type AuthProvider struct {
 	httpClient http.Client
@@ -53,40 +47,19 @@ type AuthProvider struct {
 	expiresAt time.Time
 }
 
-func NewAuthProvider() *AuthProvider {
-	if authProvider == nil {
-		once.Do(func() {
-			clientID, exists := os.LookupEnv(ClientIDEnv)
-			if !exists {
-				log.Panic("couldn't retrieve client ID")
-			}
-
-			clientSecret, exists := os.LookupEnv(ClientSecretEnv)
-			if !exists {
-				log.Panic("couldn't retrieve client secret")
-			}
-
-			refreshToken, exists := os.LookupEnv(RefreshTokenEnv)
-			if !exists {
-				log.Panic("couldn't retrieve refresh token")
-			}
-
-			authProvider = &AuthProvider{
-				clientID:     clientID,
-				clientSecret: clientSecret,
-				refreshToken: refreshToken,
-				lock:         sync.RWMutex{},
-				httpClient:   http.Client{},
-			}
-		})
+func NewAuthProvider(cfg config.Spotify) *AuthProvider {
+	return &AuthProvider{
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret.Value(),
+		refreshToken: cfg.RefreshToken.Value(),
+		lock:         sync.RWMutex{},
+		httpClient:   http.Client{Transport: newTransport()},
 	}
-
-	return authProvider
 }
 
 // GetAccessToken grants valid access tokens to the Spotify API.
 // This method is thread-safe.
-func (a *AuthProvider) GetAccessToken() (string, error) {
+func (a *AuthProvider) GetAccessToken(ctx context.Context) (string, error) {
 	a.lock.RLock()
 	if a.isTokenFresh() {
 		defer a.lock.RUnlock()
@@ -97,9 +70,9 @@ func (a *AuthProvider) GetAccessToken() (string, error) {
 	// Token is refreshed if:
 	// 1. Access token was never set.
 	// 2. Token has expired.
-	err := a.refreshAccessToken()
+	err := a.refreshAccessToken(ctx)
 	if err != nil {
-		log.Println("failed to refresh access token: ", err)
+		slog.ErrorContext(ctx, "failed to refresh access token", "err", err)
 
 		// Note that in case of failure, we return the old token.
 		// Token services commonly extend token expirations when there are outages.
@@ -113,7 +86,7 @@ func (a *AuthProvider) GetAccessToken() (string, error) {
 
 // refreshAccessToken uses the "infinite-lived" refresh token to get a new access token.
 // This method is thread-safe
-func (a *AuthProvider) refreshAccessToken() error {
+func (a *AuthProvider) refreshAccessToken(ctx context.Context) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
@@ -123,18 +96,29 @@ func (a *AuthProvider) refreshAccessToken() error {
 		return nil
 	}
 
-	req, err := a.buildRefreshTokenRequest()
+	err := a.doRefreshAccessToken(ctx)
+	metrics.ObserveTokenRefresh(err)
+	return err
+}
+
+func (a *AuthProvider) doRefreshAccessToken(ctx context.Context) error {
+	req, err := a.buildRefreshTokenRequest(ctx)
 	if err != nil {
 		return err
 	}
 
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		log.Println("failed to do refresh token request")
+		slog.ErrorContext(ctx, "failed to do refresh token request", "err", err)
 		return err
 	}
 	defer resp.Body.Close()
 
+	// Otherwise, an empty token would be cached, e.g. when the refresh token is revoked.
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("refresh token request failed with status %d", resp.StatusCode)
+	}
+
 	refreshTokenResponse, err := parseRefreshTokenResponse(resp)
 	if err != nil {
 		return err
@@ -145,12 +129,19 @@ func (a *AuthProvider) refreshAccessToken() error {
 	return nil
 }
 
+// CheckAccessToken tells whether an access token can be obtained. Fresh tokens
+// are reused, so it's cheap to call.
+func (a *AuthProvider) CheckAccessToken(ctx context.Context) error {
+	_, err := a.GetAccessToken(ctx)
+	return err
+}
+
 // Checks if the access token is still fresh
 func (a *AuthProvider) isTokenFresh() bool {
 	return time.Now().Add(TokenExpiryBuffer).Before(a.expiresAt)
 }
 
-func (a *AuthProvider) buildRefreshTokenRequest() (*http.Request, error) {
+func (a *AuthProvider) buildRefreshTokenRequest(ctx context.Context) (*http.Request, error) {
 	header := []byte(a.clientID + ":" + a.clientSecret)
 	encodedAuthorizationHeader := base64.RawStdEncoding.EncodeToString(header)
 
@@ -161,9 +152,9 @@ func (a *AuthProvider) buildRefreshTokenRequest() (*http.Request, error) {
 	encodedFormData := formData.Encode()
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", TokenEndpoint, strings.NewReader(encodedFormData))
+	req, err := http.NewRequestWithContext(ctx, "POST", TokenEndpoint, strings.NewReader(encodedFormData))
 	if err != nil {
-		log.Println("failed to create refresh token post request:", err)
+		slog.ErrorContext(ctx, "failed to create refresh token post request", "err", err)
 		return nil, err
 	}
 
@@ -177,14 +168,14 @@ func (a *AuthProvider) buildRefreshTokenRequest() (*http.Request, error) {
 func parseRefreshTokenResponse(resp *http.Response) (*spotifyapi.RefreshTokenResponse, error) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Println("failed to read refresh token response body")
+		slog.ErrorContext(resp.Request.Context(), "failed to read refresh token response body", "err", err)
 		return nil, err
 	}
 
 	var refreshTokenResponse spotifyapi.RefreshTokenResponse
 	err = json.Unmarshal(bodyBytes, &refreshTokenResponse)
 	if err != nil {
-		log.Println("failed to unmarshal access token JSON:", err)
+		slog.ErrorContext(resp.Request.Context(), "failed to unmarshal access token JSON", "err", err)
 		return nil, err
 	}
 
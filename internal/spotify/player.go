@@ -0,0 +1,134 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+	spotifyapi "github.com/jaehnri/website-backend/pkg/spotify"
+)
+
+const (
+	PlaybackStateEndpoint = "https://api.spotify.com/v1/me/player"
+
+	// ContextNameTTL is how long playlist, album and artist names are cached.
+	// Names rarely change and a playback usually sticks to the same context.
+	ContextNameTTL = time.Hour
+
+	// ContextNamesCacheSize is how many context names are cached at most.
+	ContextNamesCacheSize = 1000
+)
+
+// GetNowPlayingDetail works like GetNowPlaying, but also returns the playback
+// context, device and shuffle/repeat state.
+func (s *SpotifyClient) GetNowPlayingDetail(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	req, err := s.buildAuthorizedRequest(ctx, PlaybackStateEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Like the current playing endpoint, the player endpoint returns 204 when
+	// nothing is on. There's no playback detail to show for the last played song.
+	if resp.StatusCode == 204 {
+		return s.getLastPlayedSong(ctx)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("playback state request failed with status %d", resp.StatusCode)
+	}
+
+	var playbackState spotifyapi.PlaybackStateResponse
+	err = parseJSONResponse(resp, &playbackState)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to parse playback state response", "err", err)
+		return nil, err
+	}
+
+	song := convertPlaybackStateResponse(&playbackState)
+	if song.Detail.Context != nil {
+		song.Detail.Context.Name = s.getContextName(ctx, playbackState.Context)
+	}
+	return song, nil
+}
+
+// getContextName resolves the name of a playlist, album or artist. Failures are
+// not fatal: the context is still returned, just without a name.
+func (s *SpotifyClient) getContextName(ctx context.Context, playbackContext *spotifyapi.Context) string {
+	if name, exists := s.contextNames.Get(playbackContext.URI); exists {
+		return name
+	}
+
+	// Some contexts, e.g. "Liked Songs", can't be looked up.
+	if playbackContext.Href == "" {
+		return ""
+	}
+
+	req, err := s.buildAuthorizedRequest(ctx, playbackContext.Href)
+	if err != nil {
+		return ""
+	}
+
+	// Playlists responses are huge as they include all tracks.
+	if playbackContext.Type == "playlist" {
+		q := req.URL.Query()
+		q.Set("fields", "name")
+		req.URL.RawQuery = q.Encode()
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch playback context", "uri", playbackContext.URI, "err", err)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		slog.WarnContext(ctx, "playback context request failed", "uri", playbackContext.URI, "status", resp.StatusCode)
+		return ""
+	}
+
+	var namedObject spotifyapi.NamedObject
+	err = parseJSONResponse(resp, &namedObject)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to parse playback context", "uri", playbackContext.URI, "err", err)
+		return ""
+	}
+
+	s.contextNames.Set(playbackContext.URI, namedObject.Name)
+	return namedObject.Name
+}
+
+func convertPlaybackStateResponse(apiResponse *spotifyapi.PlaybackStateResponse) *nowplaying.CurrentSong {
+	song := convertCurrentPlayingResponse(&spotifyapi.CurrentPlayingResponse{
+		IsPlaying:  apiResponse.IsPlaying,
+		ProgressMs: apiResponse.ProgressMs,
+		Item:       apiResponse.Item,
+	})
+
+	song.Detail = &nowplaying.PlaybackDetail{
+		Device: &nowplaying.Device{
+			Name: apiResponse.Device.Name,
+			Type: apiResponse.Device.Type,
+		},
+		Shuffle: apiResponse.ShuffleState,
+		Repeat:  apiResponse.RepeatState,
+	}
+
+	if apiResponse.Context != nil {
+		song.Detail.Context = &nowplaying.PlaybackContext{
+			Type: apiResponse.Context.Type,
+			URI:  apiResponse.Context.URI,
+		}
+	}
+
+	return song
+}
@@ -1,13 +1,20 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
 
+	"github.com/jaehnri/website-backend/internal/cache"
+	"github.com/jaehnri/website-backend/internal/config"
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
 	spotifyapi "github.com/jaehnri/website-backend/pkg/spotify"
-	_ "github.com/joho/godotenv/autoload"
 )
 
 const (
@@ -15,50 +22,55 @@ const (
 	LastPlayedSongEndpoint   = "https://api.spotify.com/v1/me/player/recently-played"
 )
 
+var ErrNoRecentlyPlayed = errors.New("spotify user has no recently played songs")
+
 type SpotifyClient struct {
 	authProvider *AuthProvider
 	httpClient   http.Client
-}
 
-type CurrentSong struct {
-	IsPlaying bool `json:"is_playing"`
+	// contextNames caches playlist, album and artist names by URI.
+	contextNames *cache.TTLCache[string]
+
+	// playlists caches encoded playlist responses by request.
+	playlists *cache.TTLCache[[]byte]
+
+	// playlistsCacheControl is the Cache-Control header of playlist responses.
+	playlistsCacheControl string
 
-	ProgressMs int `json:"progress_ms"`
-	DurationMs int `json:"song_duration_ms"`
+	// userID is my Spotify user ID, lazily fetched.
+	userID string
 
-	Song   string `json:"song"`
-	Artist string `json:"artist"`
+	// userIDLock protects userID.
+	userIDLock sync.Mutex
 }
 
-func NewSpotifyClient() *SpotifyClient {
+func NewSpotifyClient(cfg config.Spotify) *SpotifyClient {
 	return &SpotifyClient{
-		authProvider: NewAuthProvider(),
-		httpClient:   http.Client{},
+		authProvider: NewAuthProvider(cfg),
+		httpClient:   http.Client{Transport: newTransport()},
+		contextNames: cache.NewTTLCache[string]("spotify_context_names", ContextNameTTL, ContextNamesCacheSize),
+		playlists:    cache.NewTTLCache[[]byte]("spotify_playlists", PlaylistsTTL, PlaylistsCacheSize),
+
+		playlistsCacheControl: cfg.PlaylistsCacheControl,
 	}
 }
 
-// HandleNowPlaying receives an HTTP request and returns the current playing song
-// in CurrentSong format.
-func (s *SpotifyClient) HandleNowPlaying(w http.ResponseWriter, r *http.Request) {
-	playingSong, err := s.getCurrentPlayingSong()
-	if err != nil {
-		http.Error(w, "failed to fetch current playing song", http.StatusInternalServerError)
-		return
-	}
+func (s *SpotifyClient) Name() string {
+	return "spotify"
+}
 
-	// TODO: Think about this later!
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(playingSong)
-	if err != nil {
-		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
-		return
-	}
+// CheckAccessToken tells whether the Spotify API can be called.
+func (s *SpotifyClient) CheckAccessToken(ctx context.Context) error {
+	return s.authProvider.CheckAccessToken(ctx)
+}
+
+// GetNowPlaying returns the current playing song or, if there's none, the last played one.
+func (s *SpotifyClient) GetNowPlaying(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	return s.getCurrentPlayingSong(ctx)
 }
 
-func (s *SpotifyClient) getCurrentPlayingSong() (*CurrentSong, error) {
-	req, err := s.buildCurrentPlayingSongRequest()
+func (s *SpotifyClient) getCurrentPlayingSong(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	req, err := s.buildCurrentPlayingSongRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +84,11 @@ func (s *SpotifyClient) getCurrentPlayingSong() (*CurrentSong, error) {
 	// Current playing endpoint returns 204 when no track is on.
 	// In this case, we can get the last played song instead.
 	if resp.StatusCode == 204 {
-		return s.getLastPlayedSong()
+		return s.getLastPlayedSong(ctx)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("current playing song request failed with status %d", resp.StatusCode)
 	}
 
 	currentPlayingResponse, err := parseCurrentPlayingSongResponse(resp)
@@ -83,36 +99,49 @@ func (s *SpotifyClient) getCurrentPlayingSong() (*CurrentSong, error) {
 	return convertCurrentPlayingResponse(currentPlayingResponse), nil
 }
 
-func (s *SpotifyClient) getLastPlayedSong() (*CurrentSong, error) {
-	req, err := s.buildLastPlayedSongRequest()
+func (s *SpotifyClient) getLastPlayedSong(ctx context.Context) (*nowplaying.CurrentSong, error) {
+	lastPlayedResponse, err := s.getRecentlyPlayed(ctx, &nowplaying.RecentlyPlayedRequest{Limit: 1})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := s.httpClient.Do(req)
+	// Fresh accounts (or accounts with private sessions only) have no history.
+	if len(lastPlayedResponse.Items) == 0 {
+		return nil, ErrNoRecentlyPlayed
+	}
+
+	return convertLastPlayedToResponse(lastPlayedResponse), nil
+}
+
+func (s *SpotifyClient) getRecentlyPlayed(ctx context.Context, req *nowplaying.RecentlyPlayedRequest) (*spotifyapi.LastPlayedResponse, error) {
+	httpReq, err := s.buildLastPlayedSongRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	lastPlayedResponse, err := parseLastPlayedSongResponse(resp)
+	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return convertLastPlayedToResponse(lastPlayedResponse), nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("recently played request failed with status %d", resp.StatusCode)
+	}
+
+	return parseLastPlayedSongResponse(resp)
 }
 
-func (s *SpotifyClient) buildCurrentPlayingSongRequest() (*http.Request, error) {
-	req, err := http.NewRequest("GET", CurrentlyPlayingEndpoint, nil)
+func (s *SpotifyClient) buildCurrentPlayingSongRequest(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", CurrentlyPlayingEndpoint, nil)
 	if err != nil {
-		log.Println("failed to create current playing song request:", err)
+		slog.ErrorContext(ctx, "failed to create current playing song request", "err", err)
 		return nil, err
 	}
 
-	accessToken, err := s.authProvider.GetAccessToken()
+	accessToken, err := s.authProvider.GetAccessToken(ctx)
 	if err != nil {
-		log.Println("failed to fetch access token:", err)
+		slog.ErrorContext(ctx, "failed to fetch access token", "err", err)
 		return nil, err
 	}
 
@@ -120,21 +149,27 @@ func (s *SpotifyClient) buildCurrentPlayingSongRequest() (*http.Request, error)
 	return req, nil
 }
 
-func (s *SpotifyClient) buildLastPlayedSongRequest() (*http.Request, error) {
-	req, err := http.NewRequest("GET", LastPlayedSongEndpoint, nil)
+func (s *SpotifyClient) buildLastPlayedSongRequest(ctx context.Context, recentlyPlayedReq *nowplaying.RecentlyPlayedRequest) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", LastPlayedSongEndpoint, nil)
 	if err != nil {
-		log.Println("failed to create current playing song request:", err)
+		slog.ErrorContext(ctx, "failed to create last played song request", "err", err)
 		return nil, err
 	}
 
-	accessToken, err := s.authProvider.GetAccessToken()
+	accessToken, err := s.authProvider.GetAccessToken(ctx)
 	if err != nil {
-		log.Println("failed to fetch access token:", err)
+		slog.ErrorContext(ctx, "failed to fetch access token", "err", err)
 		return nil, err
 	}
 
 	q := req.URL.Query()
-	q.Add("limit", "1")
+	q.Add("limit", strconv.Itoa(recentlyPlayedReq.Limit))
+	if recentlyPlayedReq.Before != "" {
+		q.Add("before", recentlyPlayedReq.Before)
+	}
+	if recentlyPlayedReq.After != "" {
+		q.Add("after", recentlyPlayedReq.After)
+	}
 	req.URL.RawQuery = q.Encode()
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -144,14 +179,14 @@ func (s *SpotifyClient) buildLastPlayedSongRequest() (*http.Request, error) {
 func parseCurrentPlayingSongResponse(resp *http.Response) (*spotifyapi.CurrentPlayingResponse, error) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Println("failed to read current playing song response body:", err)
+		slog.ErrorContext(resp.Request.Context(), "failed to read current playing song response body", "err", err)
 		return nil, err
 	}
 
 	var currentPlayingResponse spotifyapi.CurrentPlayingResponse
 	err = json.Unmarshal(bodyBytes, &currentPlayingResponse)
 	if err != nil {
-		log.Println("failed to unmarshal current playing song JSON:", err)
+		slog.ErrorContext(resp.Request.Context(), "failed to unmarshal current playing song JSON", "err", err)
 		return nil, err
 	}
 
@@ -161,22 +196,22 @@ func parseCurrentPlayingSongResponse(resp *http.Response) (*spotifyapi.CurrentPl
 func parseLastPlayedSongResponse(resp *http.Response) (*spotifyapi.LastPlayedResponse, error) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Println("failed to read last played song response body:", err)
+		slog.ErrorContext(resp.Request.Context(), "failed to read last played song response body", "err", err)
 		return nil, err
 	}
 
 	var lastPlayedResponse spotifyapi.LastPlayedResponse
 	err = json.Unmarshal(bodyBytes, &lastPlayedResponse)
 	if err != nil {
-		log.Println("failed to unmarshal current playing song JSON:", err)
+		slog.ErrorContext(resp.Request.Context(), "failed to unmarshal last played song JSON", "err", err)
 		return nil, err
 	}
 
 	return &lastPlayedResponse, nil
 }
 
-func convertCurrentPlayingResponse(apiResponse *spotifyapi.CurrentPlayingResponse) *CurrentSong {
-	return &CurrentSong{
+func convertCurrentPlayingResponse(apiResponse *spotifyapi.CurrentPlayingResponse) *nowplaying.CurrentSong {
+	return &nowplaying.CurrentSong{
 		IsPlaying: apiResponse.IsPlaying,
 
 		ProgressMs: apiResponse.ProgressMs,
@@ -186,21 +221,32 @@ func convertCurrentPlayingResponse(apiResponse *spotifyapi.CurrentPlayingRespons
 
 		// Too many artists make it ugly. The first one is enough.
 		// Plus, feats often include other artists in the name anyway.
-		Artist: apiResponse.Item.Artists[0].Name,
+		Artist: firstArtist(apiResponse.Item.Artists),
 	}
 }
 
-func convertLastPlayedToResponse(apiResponse *spotifyapi.LastPlayedResponse) *CurrentSong {
-	return &CurrentSong{
+// convertLastPlayedToResponse expects apiResponse to have at least one item.
+func convertLastPlayedToResponse(apiResponse *spotifyapi.LastPlayedResponse) *nowplaying.CurrentSong {
+	track := apiResponse.Items[0].Track
+	return &nowplaying.CurrentSong{
 		IsPlaying: false,
 
-		ProgressMs: apiResponse.Items[0].Track.DurationMs,
+		ProgressMs: track.DurationMs,
 
-		DurationMs: apiResponse.Items[0].Track.DurationMs,
-		Song:       apiResponse.Items[0].Track.Name,
+		DurationMs: track.DurationMs,
+		Song:       track.Name,
 
 		// Too many artists make it ugly. The first one is enough.
 		// Plus, feats often include other artists in the name anyway.
-		Artist: apiResponse.Items[0].Track.Artists[0].Name,
+		Artist: firstArtist(track.Artists),
+	}
+}
+
+// firstArtist returns the name of the first artist, if any. Local files and
+// podcasts might come without artists.
+func firstArtist(artists []spotifyapi.Artist) string {
+	if len(artists) == 0 {
+		return ""
 	}
+	return artists[0].Name
 }
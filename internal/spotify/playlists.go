@@ -0,0 +1,292 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jaehnri/website-backend/internal/httpcache"
+	"github.com/jaehnri/website-backend/pkg/playlists"
+	spotifyapi "github.com/jaehnri/website-backend/pkg/spotify"
+)
+
+const (
+	CurrentUserEndpoint = "https://api.spotify.com/v1/me"
+	PlaylistsEndpoint   = "https://api.spotify.com/v1/me/playlists"
+	PlaylistEndpoint    = "https://api.spotify.com/v1/playlists/"
+
+	// PlaylistsTTL is how long playlist responses are cached. Playlists change rarely.
+	PlaylistsTTL = time.Hour
+
+	// PlaylistsCacheSize is how many playlist responses are cached at most, as
+	// every page of every playlist is cached separately.
+	PlaylistsCacheSize = 1000
+
+	DefaultPlaylistTracksLimit = 50
+
+	// MaxPlaylistTracksLimit is the maximum limit accepted by the Spotify API.
+	MaxPlaylistTracksLimit = 100
+
+	// playlistsPageSize is the maximum page size accepted by the Spotify API.
+	playlistsPageSize = 50
+)
+
+var ErrPlaylistNotFound = errors.New("playlist not found")
+
+// HandlePlaylists receives an HTTP request and returns my public playlists in
+// GetPlaylistsResponse format.
+func (s *SpotifyClient) HandlePlaylists(w http.ResponseWriter, r *http.Request) {
+	body, err := s.getCachedJSON(r.URL.Path, func() (any, bool, error) {
+		response, err := s.getPlaylists(r.Context())
+		return response, true, err
+	})
+	if err != nil {
+		http.Error(w, "failed to fetch playlists", http.StatusInternalServerError)
+		return
+	}
+
+	httpcache.ServeJSON(w, r, body, s.playlistsCacheControl)
+}
+
+// HandlePlaylist receives an HTTP request and returns a public playlist with a
+// page of its tracks in GetPlaylistResponse format.
+func (s *SpotifyClient) HandlePlaylist(w http.ResponseWriter, r *http.Request) {
+	req, err := parseGetPlaylistRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := s.getCachedJSON(playlistCacheKey(req), func() (any, bool, error) {
+		response, err := s.getPlaylist(r.Context(), req)
+		if err != nil {
+			return nil, false, err
+		}
+		return response, isCacheablePage(req, response.TrackCount), nil
+	})
+	if errors.Is(err, ErrPlaylistNotFound) {
+		http.Error(w, "playlist not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch playlist", http.StatusInternalServerError)
+		return
+	}
+
+	httpcache.ServeJSON(w, r, body, s.playlistsCacheControl)
+}
+
+// getCachedJSON returns the cached JSON response for key, or fetches it and
+// caches it if fetch says it's cacheable.
+func (s *SpotifyClient) getCachedJSON(key string, fetch func() (response any, cacheable bool, err error)) ([]byte, error) {
+	if body, exists := s.playlists.Get(key); exists {
+		return body, nil
+	}
+
+	response, cacheable, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		slog.Error("failed to encode playlists response", "err", err)
+		return nil, err
+	}
+
+	if cacheable {
+		s.playlists.Set(key, body)
+	}
+	return body, nil
+}
+
+func (s *SpotifyClient) getPlaylists(ctx context.Context) (*playlists.GetPlaylistsResponse, error) {
+	userID, err := s.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &playlists.GetPlaylistsResponse{
+		Playlists: []*playlists.Playlist{},
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(playlistsPageSize)}}
+	for offset := 0; ; offset += playlistsPageSize {
+		query.Set("offset", strconv.Itoa(offset))
+
+		var page spotifyapi.PlaylistsResponse
+		err := s.getJSON(ctx, PlaylistsEndpoint, query, &page)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to fetch playlists", "err", err)
+			return nil, err
+		}
+
+		for _, playlist := range page.Items {
+			if isShowcased(&playlist, userID) {
+				response.Playlists = append(response.Playlists, convertPlaylist(&playlist))
+			}
+		}
+
+		if page.Next == "" {
+			return response, nil
+		}
+	}
+}
+
+func (s *SpotifyClient) getPlaylist(ctx context.Context, req *playlists.GetPlaylistRequest) (*playlists.GetPlaylistResponse, error) {
+	userID, err := s.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var playlist spotifyapi.Playlist
+	err = s.getJSON(ctx, PlaylistEndpoint+url.PathEscape(req.ID), url.Values{
+		"fields": {"id,name,description,public,owner(id),images,tracks(total),external_urls"},
+	}, &playlist)
+	if err != nil {
+		return nil, convertPlaylistError(err)
+	}
+
+	// Private playlists and playlists I only follow are not showcased.
+	if !isShowcased(&playlist, userID) {
+		return nil, ErrPlaylistNotFound
+	}
+
+	var tracks spotifyapi.PlaylistTracksResponse
+	err = s.getJSON(ctx, PlaylistEndpoint+url.PathEscape(req.ID)+"/tracks", url.Values{
+		"limit":  {strconv.Itoa(req.Limit)},
+		"offset": {strconv.Itoa(req.Offset)},
+		"fields": {"total,items(added_at,track(name,duration_ms,artists(name),external_urls))"},
+	}, &tracks)
+	if err != nil {
+		return nil, convertPlaylistError(err)
+	}
+
+	return convertPlaylistTracks(&playlist, &tracks, req), nil
+}
+
+// getCurrentUserID returns my Spotify user ID, used to tell my playlists apart
+// from the ones I follow.
+// This method is thread-safe.
+func (s *SpotifyClient) getCurrentUserID(ctx context.Context) (string, error) {
+	s.userIDLock.Lock()
+	defer s.userIDLock.Unlock()
+
+	// The user ID never changes, so it's fetched only once.
+	if s.userID != "" {
+		return s.userID, nil
+	}
+
+	var user spotifyapi.User
+	err := s.getJSON(ctx, CurrentUserEndpoint, nil, &user)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch current user", "err", err)
+		return "", err
+	}
+
+	s.userID = user.ID
+	return s.userID, nil
+}
+
+// playlistCacheKey identifies a page of a playlist in the cache.
+func playlistCacheKey(req *playlists.GetPlaylistRequest) string {
+	return fmt.Sprintf("playlist:%s?limit=%d&offset=%d", req.ID, req.Limit, req.Offset)
+}
+
+// isCacheablePage tells whether the page of req is worth caching: only pages
+// that clients paginating get, whose offset is a multiple of their limit, and
+// that are within the playlist. Otherwise, clients could fill the cache with
+// every possible offset.
+func isCacheablePage(req *playlists.GetPlaylistRequest, total int) bool {
+	return req.Offset%req.Limit == 0 && (req.Offset == 0 || req.Offset < total)
+}
+
+func parseGetPlaylistRequest(r *http.Request) (*playlists.GetPlaylistRequest, error) {
+	query := r.URL.Query()
+
+	limit := DefaultPlaylistTracksLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > MaxPlaylistTracksLimit {
+			return nil, errors.New("limit must be a number between 1 and 100")
+		}
+	}
+
+	offset := 0
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return nil, errors.New("offset must be a non-negative number")
+		}
+	}
+
+	return &playlists.GetPlaylistRequest{
+		ID:     r.PathValue("id"),
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+func isShowcased(playlist *spotifyapi.Playlist, userID string) bool {
+	return playlist.Public && playlist.Owner.ID == userID
+}
+
+// convertPlaylistError maps Spotify's 400 (invalid ID) and 404 to ErrPlaylistNotFound.
+func convertPlaylistError(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusBadRequest) {
+		return ErrPlaylistNotFound
+	}
+
+	slog.Error("failed to fetch playlist", "err", err)
+	return err
+}
+
+func convertPlaylist(apiPlaylist *spotifyapi.Playlist) *playlists.Playlist {
+	playlist := &playlists.Playlist{
+		ID:          apiPlaylist.ID,
+		Name:        apiPlaylist.Name,
+		Description: apiPlaylist.Description,
+		TrackCount:  apiPlaylist.Tracks.Total,
+		URL:         apiPlaylist.ExternalURLs.Spotify,
+	}
+
+	// The first image is the widest one.
+	if len(apiPlaylist.Images) > 0 {
+		playlist.CoverURL = apiPlaylist.Images[0].URL
+	}
+	return playlist
+}
+
+func convertPlaylistTracks(apiPlaylist *spotifyapi.Playlist, apiTracks *spotifyapi.PlaylistTracksResponse, req *playlists.GetPlaylistRequest) *playlists.GetPlaylistResponse {
+	tracks := make([]*playlists.Track, 0, len(apiTracks.Items))
+	for _, item := range apiTracks.Items {
+		// Tracks removed from Spotify are still listed, but without any data.
+		if item.Track == nil {
+			continue
+		}
+
+		tracks = append(tracks, &playlists.Track{
+			AddedAt:    item.AddedAt,
+			DurationMs: item.Track.DurationMs,
+			Song:       item.Track.Name,
+			Artist:     firstArtist(item.Track.Artists),
+			URL:        item.Track.ExternalURLs.Spotify,
+		})
+	}
+
+	return &playlists.GetPlaylistResponse{
+		Playlist: convertPlaylist(apiPlaylist),
+		Tracks:   tracks,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+	}
+}
@@ -0,0 +1,98 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jaehnri/website-backend/pkg/nowplaying"
+	spotifyapi "github.com/jaehnri/website-backend/pkg/spotify"
+)
+
+const (
+	DefaultRecentlyPlayedLimit = 20
+
+	// MaxRecentlyPlayedLimit is the maximum limit accepted by the Spotify API.
+	MaxRecentlyPlayedLimit = 50
+)
+
+// HandleRecentlyPlayed receives an HTTP request and returns a page of recently
+// played songs in RecentlyPlayedResponse format.
+func (s *SpotifyClient) HandleRecentlyPlayed(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRecentlyPlayedRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recentlyPlayed, err := s.getRecentlyPlayed(r.Context(), req)
+	if err != nil {
+		http.Error(w, "failed to fetch recently played songs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(convertRecentlyPlayedResponse(recentlyPlayed))
+	if err != nil {
+		http.Error(w, "failed to encode json response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func parseRecentlyPlayedRequest(r *http.Request) (*nowplaying.RecentlyPlayedRequest, error) {
+	query := r.URL.Query()
+
+	limit := DefaultRecentlyPlayedLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > MaxRecentlyPlayedLimit {
+			return nil, errors.New("limit must be a number between 1 and 50")
+		}
+	}
+
+	before := query.Get("before")
+	after := query.Get("after")
+	if before != "" && after != "" {
+		return nil, errors.New("only one of before and after can be set")
+	}
+
+	// Cursors are Unix timestamps in milliseconds.
+	for _, cursor := range []string{before, after} {
+		if _, err := strconv.ParseInt(cursor, 10, 64); cursor != "" && err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+
+	return &nowplaying.RecentlyPlayedRequest{
+		Limit:  limit,
+		Before: before,
+		After:  after,
+	}, nil
+}
+
+func convertRecentlyPlayedResponse(apiResponse *spotifyapi.LastPlayedResponse) *nowplaying.RecentlyPlayedResponse {
+	// Always return an empty list instead of null, even with no history.
+	songs := make([]*nowplaying.PlayedSong, 0, len(apiResponse.Items))
+	for _, item := range apiResponse.Items {
+		songs = append(songs, &nowplaying.PlayedSong{
+			PlayedAt:   item.PlayedAt,
+			DurationMs: item.Track.DurationMs,
+			Song:       item.Track.Name,
+			Artist:     firstArtist(item.Track.Artists),
+		})
+	}
+
+	response := &nowplaying.RecentlyPlayedResponse{
+		Songs: songs,
+		After: apiResponse.Cursors.After,
+	}
+
+	// Spotify still returns cursors in the last page, but no next URL.
+	if apiResponse.Next != "" {
+		response.Before = apiResponse.Cursors.Before
+	}
+	return response
+}
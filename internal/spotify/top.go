@@ -0,0 +1,70 @@
+package spotify
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strconv"
+
+	"github.com/jaehnri/website-backend/pkg/listening"
+	spotifyapi "github.com/jaehnri/website-backend/pkg/spotify"
+)
+
+const (
+	TopTracksEndpoint  = "https://api.spotify.com/v1/me/top/tracks"
+	TopArtistsEndpoint = "https://api.spotify.com/v1/me/top/artists"
+
+	// TopItemsTimeRange covers approximately the last 4 weeks.
+	TopItemsTimeRange = "short_term"
+
+	// TopItemsLimit is the maximum limit accepted by the Spotify API.
+	TopItemsLimit = 50
+)
+
+// GetTopTracks returns my most listened songs in the last 4 weeks.
+func (s *SpotifyClient) GetTopTracks(ctx context.Context) ([]*listening.TopTrack, error) {
+	var topTracks spotifyapi.TopTracksResponse
+	err := s.getJSON(ctx, TopTracksEndpoint, topItemsQuery(), &topTracks)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch top tracks", "err", err)
+		return nil, err
+	}
+
+	tracks := make([]*listening.TopTrack, 0, len(topTracks.Items))
+	for i, track := range topTracks.Items {
+		tracks = append(tracks, &listening.TopTrack{
+			Rank:   i + 1,
+			Song:   track.Name,
+			Artist: firstArtist(track.Artists),
+			URL:    track.ExternalURLs.Spotify,
+		})
+	}
+	return tracks, nil
+}
+
+// GetTopArtists returns my most listened artists in the last 4 weeks.
+func (s *SpotifyClient) GetTopArtists(ctx context.Context) ([]*listening.TopArtist, error) {
+	var topArtists spotifyapi.TopArtistsResponse
+	err := s.getJSON(ctx, TopArtistsEndpoint, topItemsQuery(), &topArtists)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch top artists", "err", err)
+		return nil, err
+	}
+
+	artists := make([]*listening.TopArtist, 0, len(topArtists.Items))
+	for i, artist := range topArtists.Items {
+		artists = append(artists, &listening.TopArtist{
+			Rank: i + 1,
+			Name: artist.Name,
+			URL:  artist.ExternalURLs.Spotify,
+		})
+	}
+	return artists, nil
+}
+
+func topItemsQuery() url.Values {
+	return url.Values{
+		"time_range": {TopItemsTimeRange},
+		"limit":      {strconv.Itoa(TopItemsLimit)},
+	}
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore is a GenerationStore backed by a local directory, useful for
+// self-hosting and local development. Object names may contain slashes,
+// which are mapped to subdirectories. Generations are the UnixNano of
+// modification times.
+type FileStore struct {
+	dir string
+
+	// lock makes checking generations and writing atomic. The directory is
+	// only written by this process.
+	lock sync.Mutex
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %v", dir, err)
+	}
+
+	return &FileStore{
+		dir: dir,
+	}, nil
+}
+
+func (f *FileStore) Read(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(f.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotExist
+	}
+	return data, err
+}
+
+func (f *FileStore) ReadGeneration(_ context.Context, name string) ([]byte, int64, error) {
+	path := f.path(name)
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, ErrObjectNotExist
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, info.ModTime().UnixNano(), nil
+}
+
+func (f *FileStore) Create(_ context.Context, name string, data []byte) error {
+	path := f.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see partial objects,
+	// then link it into place. Unlike a rename, linking fails if the object exists.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object %s: %v", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	err = os.Link(tmp.Name(), path)
+	if errors.Is(err, fs.ErrExist) {
+		return ErrObjectExists
+	}
+	return err
+}
+
+func (f *FileStore) Write(_ context.Context, name string, data []byte, ifGeneration int64) (int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	path := f.path(name)
+	if err := checkGeneration(path, ifGeneration); err != nil {
+		return 0, err
+	}
+
+	generation, err := WriteFileGeneration(path, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write object %s: %v", name, err)
+	}
+	return generation, nil
+}
+
+func (f *FileStore) Delete(_ context.Context, name string, ifGeneration int64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	path := f.path(name)
+	if err := checkGeneration(path, ifGeneration); err != nil && !errors.Is(err, ErrObjectNotExist) {
+		return err
+	}
+
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrObjectNotExist
+	}
+	return err
+}
+
+// checkGeneration returns ErrPreconditionFailed unless the file at path is at
+// ifGeneration, like GenerationStore.Write says. It returns ErrObjectNotExist
+// if the file must exist but doesn't.
+func checkGeneration(path string, ifGeneration int64) error {
+	if ifGeneration == AnyGeneration {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if ifGeneration == 0 {
+			return nil
+		}
+		return ErrObjectNotExist
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.ModTime().UnixNano() != ifGeneration {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+func (f *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	generations, err := f.ListGenerations(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(generations))
+	for name := range generations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (f *FileStore) ListGenerations(_ context.Context, prefix string) (map[string]int64, error) {
+	generations := make(map[string]int64)
+
+	err := filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		generations[name] = info.ModTime().UnixNano()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects with prefix %s: %v", prefix, err)
+	}
+
+	return generations, nil
+}
+
+// Check stats the directory.
+func (f *FileStore) Check(_ context.Context) error {
+	_, err := os.Stat(f.dir)
+	return err
+}
+
+func (f *FileStore) path(name string) string {
+	return filepath.Join(f.dir, filepath.FromSlash(name))
+}
+
+// WriteFileGeneration writes data to path atomically, and returns its new
+// generation, the UnixNano of its modification time. Some filesystems only
+// update modification times every few milliseconds, so it's set explicitly
+// to keep generations unique.
+func WriteFileGeneration(path string, data []byte) (int64, error) {
+	if err := WriteFileAtomically(path, data); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return 0, err
+	}
+	return now.UnixNano(), nil
+}
+
+// WriteFileAtomically writes to a temporary file and renames it, so readers
+// never see a partially written file.
+func WriteFileAtomically(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/jaehnri/website-backend/internal/metrics"
+	"github.com/jaehnri/website-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStore is a GenerationStore backed by a GCS bucket. Object names are
+// prefixed with prefix in the bucket.
+type GCSStore struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func NewGCSStore(bucketName string, prefix string) (*GCSStore, error) {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %v", err)
+	}
+
+	return &GCSStore{
+		bucket: client.Bucket(bucketName),
+		prefix: prefix,
+	}, nil
+}
+
+func (g *GCSStore) Read(ctx context.Context, name string) ([]byte, error) {
+	data, _, err := g.ReadGeneration(ctx, name)
+	return data, err
+}
+
+func (g *GCSStore) ReadGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	ctx, span := tracing.Start(ctx, "gcs.read", g.objectAttribute(name))
+	start := time.Now()
+	data, generation, err := g.read(ctx, name)
+	metrics.ObserveGCS("read", start, err)
+	tracing.End(span, err)
+
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, 0, ErrObjectNotExist
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read object %s: %v", name, err)
+	}
+	return data, generation, nil
+}
+
+func (g *GCSStore) read(ctx context.Context, name string) ([]byte, int64, error) {
+	rc, err := g.bucket.Object(g.prefix + name).NewReader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	return data, rc.Attrs.Generation, err
+}
+
+func (g *GCSStore) Create(ctx context.Context, name string, data []byte) error {
+	_, err := g.Write(ctx, name, data, 0)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return ErrObjectExists
+	}
+	return err
+}
+
+func (g *GCSStore) Write(ctx context.Context, name string, data []byte, ifGeneration int64) (int64, error) {
+	ctx, span := tracing.Start(ctx, "gcs.write", g.objectAttribute(name))
+	start := time.Now()
+	generation, err := g.write(ctx, name, data, ifGeneration)
+	metrics.ObserveGCS("write", start, err)
+	tracing.End(span, err)
+
+	if isPreconditionFailed(err) {
+		return 0, ErrPreconditionFailed
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write object %s: %v", name, err)
+	}
+	return generation, nil
+}
+
+func (g *GCSStore) write(ctx context.Context, name string, data []byte, ifGeneration int64) (int64, error) {
+	wc := g.object(name, ifGeneration).NewWriter(ctx)
+	wc.ContentType = "application/json"
+
+	if _, err := wc.Write(data); err != nil {
+		wc.Close()
+		return 0, err
+	}
+	if err := wc.Close(); err != nil {
+		return 0, err
+	}
+	return wc.Attrs().Generation, nil
+}
+
+func (g *GCSStore) Delete(ctx context.Context, name string, ifGeneration int64) error {
+	ctx, span := tracing.Start(ctx, "gcs.delete", g.objectAttribute(name))
+	start := time.Now()
+	err := g.object(name, ifGeneration).Delete(ctx)
+	metrics.ObserveGCS("delete", start, err)
+	tracing.End(span, err)
+
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrObjectNotExist
+	}
+	if isPreconditionFailed(err) {
+		return ErrPreconditionFailed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %v", name, err)
+	}
+	return nil
+}
+
+// object returns the handle of the object with name, with the conditions of
+// ifGeneration. Objects must not exist yet for generation 0.
+func (g *GCSStore) object(name string, ifGeneration int64) *storage.ObjectHandle {
+	object := g.bucket.Object(g.prefix + name)
+	switch {
+	case ifGeneration == 0:
+		return object.If(storage.Conditions{DoesNotExist: true})
+	case ifGeneration > 0:
+		return object.If(storage.Conditions{GenerationMatch: ifGeneration})
+	}
+	return object
+}
+
+// isPreconditionFailed tells whether err is a 412 of GCS, returned by
+// requests with conditions that don't hold.
+func isPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
+}
+
+func (g *GCSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	generations, err := g.ListGenerations(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(generations))
+	for name := range generations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (g *GCSStore) ListGenerations(ctx context.Context, prefix string) (map[string]int64, error) {
+	ctx, span := tracing.Start(ctx, "gcs.list", attribute.String("gcs.prefix", g.prefix+prefix))
+	start := time.Now()
+	generations, err := g.list(ctx, prefix)
+	metrics.ObserveGCS("list", start, err)
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects with prefix %s: %v", prefix, err)
+	}
+	return generations, nil
+}
+
+func (g *GCSStore) list(ctx context.Context, prefix string) (map[string]int64, error) {
+	generations := make(map[string]int64)
+
+	it := g.bucket.Objects(ctx, &storage.Query{Prefix: g.prefix + prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		generations[strings.TrimPrefix(attrs.Name, g.prefix)] = attrs.Generation
+	}
+
+	return generations, nil
+}
+
+// Check reads the bucket metadata.
+func (g *GCSStore) Check(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "gcs.attrs", attribute.String("gcs.prefix", g.prefix))
+	start := time.Now()
+	_, err := g.bucket.Attrs(ctx)
+	metrics.ObserveGCS("attrs", start, err)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (g *GCSStore) objectAttribute(name string) attribute.KeyValue {
+	return attribute.String("gcs.object", g.prefix+name)
+}
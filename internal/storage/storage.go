@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrObjectNotExist = errors.New("object does not exist")
+	ErrObjectExists   = errors.New("object already exists")
+
+	// ErrPreconditionFailed is returned by writes and deletes of objects that
+	// aren't at the generation they're conditioned on.
+	ErrPreconditionFailed = errors.New("object generation doesn't match")
+)
+
+// AnyGeneration makes writes and deletes of objects unconditional.
+const AnyGeneration = -1
+
+// ObjectStore stores immutable objects, either in GCS or in the local filesystem.
+type ObjectStore interface {
+	// Read returns the contents of an object, or ErrObjectNotExist.
+	Read(ctx context.Context, name string) ([]byte, error)
+
+	// Create writes a new object. Objects are immutable: if it already exists,
+	// ErrObjectExists is returned and the object is left untouched.
+	Create(ctx context.Context, name string, data []byte) error
+
+	// List returns the names of all objects starting with prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// GenerationStore is an ObjectStore whose objects can also be overwritten and
+// deleted. Every write of an object gives it a new generation, and writes and
+// deletes can be conditioned on it, so concurrent ones, e.g. of other
+// replicas, don't overwrite each other.
+type GenerationStore interface {
+	ObjectStore
+
+	// ReadGeneration returns the contents and the generation of an object, or
+	// ErrObjectNotExist.
+	ReadGeneration(ctx context.Context, name string) ([]byte, int64, error)
+
+	// Write creates or overwrites an object, returning its new generation.
+	// Unless ifGeneration is AnyGeneration, the object must be at that
+	// generation, or not exist if it's 0, or ErrPreconditionFailed is returned.
+	Write(ctx context.Context, name string, data []byte, ifGeneration int64) (int64, error)
+
+	// Delete removes an object, or returns ErrObjectNotExist. The object must
+	// be at ifGeneration, like with Write.
+	Delete(ctx context.Context, name string, ifGeneration int64) error
+
+	// ListGenerations returns the generation of every object whose name
+	// starts with prefix.
+	ListGenerations(ctx context.Context, prefix string) (map[string]int64, error)
+
+	// Check tells whether the store is reachable.
+	Check(ctx context.Context) error
+}
@@ -0,0 +1,101 @@
+package feed
+
+import "encoding/xml"
+
+// RSS is an RSS 2.0 document. See https://www.rssboard.org/rss-specification.
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+
+	// Self points to the feed itself, as recommended by the RSS Advisory Board.
+	Self AtomLink `xml:"atom:link"`
+
+	// LastBuildDate is formatted as RFC 1123 with a numeric zone.
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*RSSItem `xml:"item"`
+}
+
+type RSSItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        RSSGUID `xml:"guid"`
+
+	// PubDate is formatted as RFC 1123 with a numeric zone.
+	PubDate string `xml:"pubDate"`
+}
+
+type RSSGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// AtomFeed is an Atom 1.0 document. See RFC 4287.
+type AtomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Author   *AtomPerson  `xml:"author,omitempty"`
+	Links    []*AtomLink  `xml:"link"`
+	Entries  []*AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Links     []*AtomLink `xml:"link"`
+	Content   AtomContent `xml:"content"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// JSONFeed is a JSON Feed 1.1 document. See https://www.jsonfeed.org/version/1.1/.
+type JSONFeed struct {
+	Version     string          `json:"version"`
+	Title       string          `json:"title"`
+	HomePageURL string          `json:"home_page_url,omitempty"`
+	FeedURL     string          `json:"feed_url,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Authors     []*JSONAuthor   `json:"authors,omitempty"`
+	Items       []*JSONFeedItem `json:"items"`
+}
+
+type JSONAuthor struct {
+	Name string `json:"name"`
+}
+
+type JSONFeedItem struct {
+	ID          string `json:"id"`
+	URL         string `json:"url,omitempty"`
+	Title       string `json:"title,omitempty"`
+	ContentText string `json:"content_text,omitempty"`
+	ContentHTML string `json:"content_html,omitempty"`
+
+	// DatePublished is formatted as RFC 3339.
+	DatePublished string `json:"date_published"`
+}
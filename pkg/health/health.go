@@ -0,0 +1,43 @@
+package health
+
+import "time"
+
+// Health statuses.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	// StatusDegraded is reported by optional checks that fail, e.g. of a
+	// module that is misconfigured, and by GET /readyz when only those fail.
+	// They don't fail readiness.
+	StatusDegraded = "degraded"
+
+	// StatusShuttingDown is reported by GET /readyz once the server received
+	// a termination signal, so load balancers stop sending new requests.
+	StatusShuttingDown = "shutting_down"
+)
+
+// HealthResponse is the HTTP response for GET /healthz.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadinessResponse is the HTTP response for GET /readyz.
+type ReadinessResponse struct {
+	Status string   `json:"status"`
+	Checks []*Check `json:"checks"`
+}
+
+// Check is the last result of a dependency check, e.g. whether a Spotify
+// access token can be obtained.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// LatencyMs is how long the check took.
+	LatencyMs int64 `json:"latency_ms"`
+
+	// CheckedAt is when the check ran. Results are cached for a while, so
+	// probes don't hammer upstreams.
+	CheckedAt time.Time `json:"checked_at"`
+}
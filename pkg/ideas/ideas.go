@@ -2,8 +2,18 @@ package ideas
 
 import "time"
 
+// RenderHTML is the value of GetIdeasRequest.Render that renders ideas as HTML.
+const RenderHTML = "html"
+
+// Statuses of ideas. Only published ideas are public.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+)
+
 // GetIdeasRequest is the HTTP request response for GET /ideas.
-// Ideas can be filtered using Limit and Offset.
+// Ideas can be paginated using Limit and either Cursor or Offset.
 type GetIdeasRequest struct {
 	// How many ideas to fetch. Defaults to 50.
 	Limit int `json:"limit"`
@@ -11,17 +21,74 @@ type GetIdeasRequest struct {
 	// GET /ideas always fetches newest ideas. Increase offset
 	// to fetch older ones.
 	Offset int `json:"offset"`
+
+	// Cursor is the NextCursor of the previous page. Unlike offsets, cursors
+	// don't shift when new ideas are posted.
+	Cursor string `json:"cursor,omitempty"`
+
+	// Since and Until only fetch ideas posted at or after Since and before
+	// Until, if set.
+	Since time.Time `json:"since,omitzero"`
+	Until time.Time `json:"until,omitzero"`
+
+	// Query searches ideas by text, e.g. `go "generic types"`. Quoted words
+	// must appear in a row. Matches are sorted by relevance instead of time.
+	Query string `json:"q,omitempty"`
+
+	// Tag only fetches ideas with this tag.
+	Tag string `json:"tag,omitempty"`
+
+	// Render is RenderHTML to include the rendered HTML of ideas.
+	Render string `json:"render,omitempty"`
 }
 
 // GetIdeasResponse is the HTTP response for GET /ideas.
 // Ideas are sortered from newest to oldest.
 type GetIdeasResponse struct {
 	Ideas []*Idea `json:"ideas"`
+
+	// NextCursor fetches the next page. It's empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Total is how many ideas match the filters, in all pages.
+	Total int `json:"total"`
+}
+
+// SearchIdeasResponse is the HTTP response for GET /ideas?q=.
+// Ideas are sorted from most to least relevant.
+type SearchIdeasResponse struct {
+	Ideas []*IdeaMatch `json:"ideas"`
+
+	// Total is how many ideas match, in all pages.
+	Total int `json:"total"`
+}
+
+// IdeaMatch is an idea that matches a search.
+type IdeaMatch struct {
+	*Idea
+
+	// Highlight is the idea as HTML, with matching words wrapped in <mark>.
+	Highlight string `json:"highlight"`
+
+	// Score tells how relevant the idea is. It's only meaningful next to the
+	// scores of other ideas of the same search.
+	Score float64 `json:"score"`
 }
 
 // PostIdeaResponse is the HTTP request for POST /ideas.
 type PostIdeaRequest struct {
+	// Idea is Markdown, limited to links, emphasis, inline code and lists.
 	Idea string `json:"idea"`
+
+	// Tags are added to the #hashtags of the idea.
+	Tags []string `json:"tags,omitempty"`
+
+	// Status is either StatusDraft or StatusPublished, the default.
+	Status string `json:"status,omitempty"`
+
+	// PublishAt schedules the idea to be published later. Drafts with a
+	// PublishAt are scheduled too.
+	PublishAt time.Time `json:"publish_at,omitzero"`
 }
 
 // PostIdeaResponse is the HTTP response for POST /ideas.
@@ -31,6 +98,63 @@ type PostIdeaResponse struct {
 
 // Idea represents an idea that I had. Or a thought. Or something.
 type Idea struct {
+	// ID is derived from the time and the text of the idea, so it's stable
+	// even for ideas stored before IDs existed.
+	ID string `json:"id"`
+
+	// Time is when the idea was or will be published.
 	Time time.Time `json:"time"`
 	Idea string    `json:"idea"`
+
+	// Status is StatusDraft, StatusScheduled or StatusPublished. Ideas stored
+	// before statuses existed have none, and are published.
+	Status string `json:"status,omitempty"`
+
+	// Tags are lowercase, without #. They're the explicit tags of the idea
+	// followed by its #hashtags.
+	Tags []string `json:"tags,omitempty"`
+
+	// DeletedAt is when the idea was moved to the trash. Deleted ideas are
+	// purged for good after some time.
+	DeletedAt time.Time `json:"deleted_at,omitzero"`
+
+	// HTML is the idea rendered from Markdown and sanitized. It's never
+	// stored, only included in responses when requested.
+	HTML string `json:"html,omitempty"`
+}
+
+// Revision is a version of the stored ideas. Every write creates one.
+type Revision struct {
+	// Generation identifies the revision. Newer revisions have higher ones.
+	Generation int64     `json:"generation"`
+	Time       time.Time `json:"time"`
+	Size       int64     `json:"size"`
+
+	// Current tells whether this is the revision being served.
+	Current bool `json:"current"`
+}
+
+// GetRevisionsResponse is the HTTP response for GET /ideas/revisions.
+// Revisions are sorted from newest to oldest.
+type GetRevisionsResponse struct {
+	Revisions []*Revision `json:"revisions"`
+}
+
+// GetRevisionResponse is the HTTP response for GET /ideas/revisions/{gen}.
+// It holds every idea of the revision, including drafts and deleted ones.
+type GetRevisionResponse struct {
+	*Revision
+	Ideas []*Idea `json:"ideas"`
+}
+
+// GetTagsResponse is the HTTP response for GET /ideas/tags.
+// Tags are sorted from most to least used.
+type GetTagsResponse struct {
+	Tags []*TagCount `json:"tags"`
+}
+
+// TagCount tells how many ideas have a tag.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
 }
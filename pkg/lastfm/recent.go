@@ -0,0 +1,50 @@
+package lastfm
+
+import "encoding/json"
+
+// Expected response for the user.getRecentTracks method.
+// See https://www.last.fm/api/show/user.getRecentTracks.
+type RecentTracksResponse struct {
+	RecentTracks RecentTracks `json:"recenttracks"`
+}
+
+// RecentTracks wraps the list of scrobbled tracks.
+type RecentTracks struct {
+	Tracks Tracks `json:"track"`
+}
+
+// Tracks is a list of tracks. Last.fm returns a single object instead of an
+// array when there's only one track, so both shapes are accepted.
+type Tracks []Track
+
+func (t *Tracks) UnmarshalJSON(data []byte) error {
+	var tracks []Track
+	if err := json.Unmarshal(data, &tracks); err == nil {
+		*t = tracks
+		return nil
+	}
+
+	var track Track
+	if err := json.Unmarshal(data, &track); err != nil {
+		return err
+	}
+	*t = Tracks{track}
+	return nil
+}
+
+// Track represents a scrobbled (or currently scrobbling) song.
+type Track struct {
+	Name   string      `json:"name"`
+	Artist Artist      `json:"artist"`
+	Attr   *TrackAttrs `json:"@attr,omitempty"`
+}
+
+// Artist of a given Track.
+type Artist struct {
+	Name string `json:"#text"`
+}
+
+// TrackAttrs is only present on the track that is currently playing.
+type TrackAttrs struct {
+	NowPlaying string `json:"nowplaying"`
+}
@@ -0,0 +1,33 @@
+package listenbrainz
+
+// Expected response for /1/user/{user}/playing-now and /1/user/{user}/listens.
+// See https://listenbrainz.readthedocs.io/en/latest/users/api/core.html.
+type ListensResponse struct {
+	Payload Payload `json:"payload"`
+}
+
+// Payload holds the listens of a given user.
+type Payload struct {
+	Count      int      `json:"count"`
+	PlayingNow bool     `json:"playing_now"`
+	Listens    []Listen `json:"listens"`
+}
+
+// Listen represents a single listen, either submitted or currently playing.
+type Listen struct {
+	ListenedAt    int64         `json:"listened_at"`
+	PlayingNow    bool          `json:"playing_now"`
+	TrackMetadata TrackMetadata `json:"track_metadata"`
+}
+
+// TrackMetadata describes the song being listened to.
+type TrackMetadata struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	AdditionalInfo AdditionalInfo `json:"additional_info"`
+}
+
+// AdditionalInfo holds optional metadata sent by the scrobbler.
+type AdditionalInfo struct {
+	DurationMs int `json:"duration_ms"`
+}
@@ -0,0 +1,43 @@
+package listening
+
+import "time"
+
+// MonthlySnapshot is an immutable record of my top tracks and artists in a
+// given month. It's the HTTP response for GET /listening/monthly/{yyyy-mm}.
+type MonthlySnapshot struct {
+	// Month is formatted as yyyy-mm.
+	Month   string    `json:"month"`
+	TakenAt time.Time `json:"taken_at"`
+
+	Tracks  []*TopTrack  `json:"tracks"`
+	Artists []*TopArtist `json:"artists"`
+}
+
+// WrappedResponse is the HTTP response for GET /listening/wrapped/{year}.
+// It aggregates all monthly snapshots of a year.
+type WrappedResponse struct {
+	Year int `json:"year"`
+
+	// Months lists which months had a snapshot, formatted as yyyy-mm.
+	Months []string `json:"months"`
+
+	Tracks  []*TopTrack  `json:"tracks"`
+	Artists []*TopArtist `json:"artists"`
+}
+
+// TopTrack is one of my most listened songs. Rank starts at 1.
+type TopTrack struct {
+	Rank int `json:"rank"`
+
+	Song   string `json:"song"`
+	Artist string `json:"artist"`
+	URL    string `json:"url"`
+}
+
+// TopArtist is one of my most listened artists. Rank starts at 1.
+type TopArtist struct {
+	Rank int `json:"rank"`
+
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
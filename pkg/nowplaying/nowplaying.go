@@ -0,0 +1,79 @@
+package nowplaying
+
+import "time"
+
+// CurrentSong is the HTTP response for GET /now-playing.
+// It is provider-agnostic: Spotify, Last.fm and ListenBrainz are all converted into it.
+type CurrentSong struct {
+	IsPlaying bool `json:"is_playing"`
+
+	ProgressMs int `json:"progress_ms"`
+	DurationMs int `json:"song_duration_ms"`
+
+	Song   string `json:"song"`
+	Artist string `json:"artist"`
+
+	// Detail is only filled in GET /now-playing?detail=full, by providers that support it.
+	Detail *PlaybackDetail `json:"detail,omitempty"`
+}
+
+// PlaybackDetail describes where and how the current song is being played.
+type PlaybackDetail struct {
+	// Context is nil when the song isn't played from a playlist, album or artist.
+	Context *PlaybackContext `json:"context,omitempty"`
+	Device  *Device          `json:"device,omitempty"`
+
+	Shuffle bool `json:"shuffle"`
+
+	// Repeat is one of "off", "track" or "context".
+	Repeat string `json:"repeat"`
+}
+
+// PlaybackContext is what the playback was started from.
+type PlaybackContext struct {
+	// Type is one of "playlist", "album", "artist" or "show".
+	Type string `json:"type"`
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// Device is the device the playback is happening on.
+// Volume is purposely left out.
+type Device struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RecentlyPlayedRequest is the HTTP request for GET /recently-played.
+// Only one of Before and After can be set.
+type RecentlyPlayedRequest struct {
+	// How many songs to fetch. Defaults to 20, at most 50.
+	Limit int `json:"limit"`
+
+	// Before is a cursor returned by a previous request to fetch older songs.
+	Before string `json:"before"`
+
+	// After is a cursor returned by a previous request to fetch newer songs.
+	After string `json:"after"`
+}
+
+// RecentlyPlayedResponse is the HTTP response for GET /recently-played.
+// Songs are sorted from newest to oldest.
+type RecentlyPlayedResponse struct {
+	Songs []*PlayedSong `json:"songs"`
+
+	// Before is the cursor to the next (older) page. Empty when there's none.
+	Before string `json:"before,omitempty"`
+
+	// After is the cursor to the previous (newer) page.
+	After string `json:"after,omitempty"`
+}
+
+// PlayedSong is a song that was played until the end.
+type PlayedSong struct {
+	PlayedAt   time.Time `json:"played_at"`
+	DurationMs int       `json:"song_duration_ms"`
+
+	Song   string `json:"song"`
+	Artist string `json:"artist"`
+}
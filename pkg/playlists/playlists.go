@@ -0,0 +1,49 @@
+package playlists
+
+import "time"
+
+// GetPlaylistsResponse is the HTTP response for GET /playlists.
+type GetPlaylistsResponse struct {
+	Playlists []*Playlist `json:"playlists"`
+}
+
+// GetPlaylistRequest is the HTTP request for GET /playlists/{id}.
+// Tracks can be paginated using Limit and Offset.
+type GetPlaylistRequest struct {
+	ID string `json:"id"`
+
+	// How many tracks to fetch. Defaults to 50, at most 100.
+	Limit int `json:"limit"`
+
+	// Increase offset to fetch the next tracks.
+	Offset int `json:"offset"`
+}
+
+// GetPlaylistResponse is the HTTP response for GET /playlists/{id}.
+type GetPlaylistResponse struct {
+	*Playlist
+
+	Tracks []*Track `json:"tracks"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+// Playlist is one of my public Spotify playlists.
+type Playlist struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	CoverURL    string `json:"cover_url,omitempty"`
+	TrackCount  int    `json:"track_count"`
+	URL         string `json:"url"`
+}
+
+// Track is a song in a playlist.
+type Track struct {
+	AddedAt    time.Time `json:"added_at"`
+	DurationMs int       `json:"song_duration_ms"`
+
+	Song   string `json:"song"`
+	Artist string `json:"artist"`
+	URL    string `json:"url"`
+}
@@ -0,0 +1,34 @@
+package spotify
+
+// Expected response for https://api.spotify.com/v1/me/player.
+// See https://developer.spotify.com/documentation/web-api/reference/get-information-about-the-users-current-playback.
+type PlaybackStateResponse struct {
+	IsPlaying    bool     `json:"is_playing"`
+	ProgressMs   int      `json:"progress_ms"`
+	Item         Item     `json:"item"`
+	Device       Device   `json:"device"`
+	ShuffleState bool     `json:"shuffle_state"`
+	RepeatState  string   `json:"repeat_state"`
+	Context      *Context `json:"context"`
+}
+
+// Device is the device the playback is happening on.
+type Device struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Context is what the playback was started from, e.g. a playlist or an album.
+type Context struct {
+	Type string `json:"type"`
+	URI  string `json:"uri"`
+
+	// Href is the Web API endpoint describing the context.
+	Href string `json:"href"`
+}
+
+// NamedObject is the subset of a playlist, album or artist response that
+// is needed to show a playback context.
+type NamedObject struct {
+	Name string `json:"name"`
+}
@@ -1,5 +1,7 @@
 package spotify
 
+import "time"
+
 // Expected response for https://api.spotify.com/v1/me/player/currently-playing.
 // See https://developer.spotify.com/documentation/web-api/reference/get-the-users-currently-playing-track.
 type CurrentPlayingResponse struct {
@@ -24,16 +26,28 @@ type Artist struct {
 // See https://developer.spotify.com/documentation/web-api/reference/get-recently-played.
 type LastPlayedResponse struct {
 	Items []PlayedItem `json:"items"`
+
+	// Next is the URL of the next (older) page. Empty when there's none.
+	Next    string  `json:"next"`
+	Cursors Cursors `json:"cursors"`
+}
+
+// Cursors are Unix timestamps in milliseconds used to page through played items.
+type Cursors struct {
+	After  string `json:"after"`
+	Before string `json:"before"`
 }
 
 // PlayedItem represents a recently played track.
 type PlayedItem struct {
-	Track Track `json:"track"`
+	Track    Track     `json:"track"`
+	PlayedAt time.Time `json:"played_at"`
 }
 
 // Track represents a song.
 type Track struct {
-	Name       string   `json:"name"`
-	Artists    []Artist `json:"artists"`
-	DurationMs int      `json:"duration_ms"`
+	Name         string       `json:"name"`
+	Artists      []Artist     `json:"artists"`
+	DurationMs   int          `json:"duration_ms"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
 }
@@ -0,0 +1,62 @@
+package spotify
+
+import "time"
+
+// Expected response for https://api.spotify.com/v1/me.
+// See https://developer.spotify.com/documentation/web-api/reference/get-current-users-profile.
+type User struct {
+	ID string `json:"id"`
+}
+
+// Expected response for https://api.spotify.com/v1/me/playlists.
+// See https://developer.spotify.com/documentation/web-api/reference/get-a-list-of-current-users-playlists.
+type PlaylistsResponse struct {
+	Items []Playlist `json:"items"`
+
+	// Next is the URL of the next page. Empty when there's none.
+	Next string `json:"next"`
+}
+
+// Playlist is a simplified playlist, as returned by both the playlists listing
+// and https://api.spotify.com/v1/playlists/{id}.
+// See https://developer.spotify.com/documentation/web-api/reference/get-playlist.
+type Playlist struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Description  string       `json:"description"`
+	Public       bool         `json:"public"`
+	Owner        User         `json:"owner"`
+	Images       []Image      `json:"images"`
+	Tracks       TracksRef    `json:"tracks"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
+}
+
+// TracksRef is a reference to the tracks of a playlist.
+type TracksRef struct {
+	Total int `json:"total"`
+}
+
+// Image is a cover art. Spotify returns them from widest to narrowest.
+type Image struct {
+	URL string `json:"url"`
+}
+
+// ExternalURLs holds the links to open an object in Spotify.
+type ExternalURLs struct {
+	Spotify string `json:"spotify"`
+}
+
+// Expected response for https://api.spotify.com/v1/playlists/{id}/tracks.
+// See https://developer.spotify.com/documentation/web-api/reference/get-playlists-tracks.
+type PlaylistTracksResponse struct {
+	Items []PlaylistItem `json:"items"`
+	Total int            `json:"total"`
+}
+
+// PlaylistItem is a track added to a playlist.
+type PlaylistItem struct {
+	AddedAt time.Time `json:"added_at"`
+
+	// Track is nil when the track is no longer available.
+	Track *Track `json:"track"`
+}
@@ -0,0 +1,18 @@
+package spotify
+
+// Expected response for https://api.spotify.com/v1/me/top/tracks.
+// See https://developer.spotify.com/documentation/web-api/reference/get-users-top-artists-and-tracks.
+type TopTracksResponse struct {
+	Items []Track `json:"items"`
+}
+
+// Expected response for https://api.spotify.com/v1/me/top/artists.
+type TopArtistsResponse struct {
+	Items []TopArtist `json:"items"`
+}
+
+// TopArtist is a full artist object, as opposed to the simplified Artist.
+type TopArtist struct {
+	Name         string       `json:"name"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
+}
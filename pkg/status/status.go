@@ -0,0 +1,28 @@
+package status
+
+// Module statuses.
+const (
+	// ModuleEnabled modules are up and serving their routes.
+	ModuleEnabled = "enabled"
+
+	// ModuleDisabled modules were turned off in the configuration.
+	ModuleDisabled = "disabled"
+
+	// ModuleDegraded modules are enabled, but failed to start. Their routes
+	// are not served.
+	ModuleDegraded = "degraded"
+)
+
+// StatusResponse is the HTTP response for GET /status.
+type StatusResponse struct {
+	Modules []*Module `json:"modules"`
+}
+
+// Module is a feature of the server, e.g. Spotify or ideas.
+type Module struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// Error explains why a module is degraded.
+	Error string `json:"error,omitempty"`
+}